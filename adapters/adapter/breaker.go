@@ -0,0 +1,117 @@
+package adapter
+
+import (
+	"sync"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+type BreakerConfig struct {
+	// FailureThreshold - сколько ошибок подряд размыкают цепь
+	FailureThreshold int
+	// CoolDown - сколько цепь остаётся разомкнутой до пробных запросов
+	CoolDown time.Duration
+	// HalfOpenProbes - сколько пробных запросов пропускается в полуоткрытом состоянии
+	HalfOpenProbes int
+}
+
+// CircuitBreaker - автомат closed/open/half-open. После FailureThreshold ошибок подряд
+// запросы сразу отклоняются с entity.ErrCircuitOpen, пока не пройдёт CoolDown;
+// затем пропускается HalfOpenProbes пробных запросов, и успех снова замыкает цепь.
+type CircuitBreaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probes   int
+}
+
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = 30 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// Allow разрешает вызов или возвращает entity.ErrCircuitOpen.
+// Каждый разрешённый вызов должен закончиться Record или Release.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cfg.CoolDown {
+			return entity.ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return entity.ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record учитывает результат вызова: failure == true - ошибка провайдера
+func (b *CircuitBreaker) Record(failure bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failure {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.cfg.FailureThreshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// Release завершает разрешённый вызов без вердикта, например отменённый клиентом:
+// счётчик ошибок не меняется, а в полуоткрытом состоянии освобождается место пробы.
+func (b *CircuitBreaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cfg.CoolDown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
@@ -0,0 +1,64 @@
+package adapter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 2, CoolDown: 20 * time.Millisecond})
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("expected closed breaker to allow call %d", i)
+		}
+		b.Record(true)
+	}
+	if err := b.Allow(); !errors.Is(err, entity.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("expected half-open after cool-down, got %s", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatal("expected probe to be allowed")
+	}
+	if err := b.Allow(); err == nil {
+		t.Error("expected only one probe in half-open state")
+	}
+	b.Record(false)
+	if b.State() != BreakerClosed {
+		t.Errorf("expected successful probe to close breaker, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+	b := NewCircuitBreaker(BreakerConfig{FailureThreshold: 2, CoolDown: 20 * time.Millisecond})
+
+	b.Allow()
+	b.Record(true)
+	b.Allow()
+	b.Release()
+	if b.State() != BreakerClosed {
+		t.Fatalf("expected released call not to count, got %s", b.State())
+	}
+	b.Allow()
+	b.Record(true)
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected released call not to reset failures, got %s", b.State())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Allow(); err != nil {
+		t.Fatal("expected probe to be allowed")
+	}
+	b.Release()
+	if err := b.Allow(); err != nil {
+		t.Errorf("expected released probe to free its slot, got %v", err)
+	}
+}
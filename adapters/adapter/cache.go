@@ -1,6 +1,8 @@
 package adapter
 
 import (
+	"container/list"
+	"encoding/json"
 	"log"
 	"net/http"
 	"sync"
@@ -12,45 +14,279 @@ type Server struct {
 	cache *Cache
 }
 
+// CacheOption настраивает ограничения кэша
+type CacheOption func(*Cache)
+
+// WithMaxEntries ограничивает количество записей в кэше, лишние вытесняются по LRU
+func WithMaxEntries(n int) CacheOption {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
+
+// WithMaxBytes ограничивает примерный объём данных в кэше (ключи + значения)
+func WithMaxBytes(n int64) CacheOption {
+	return func(c *Cache) {
+		c.maxBytes = n
+	}
+}
+
+// WithSizeFunc задаёт функцию оценки размера записи для WithMaxBytes
+func WithSizeFunc(f func(key string, value interface{}) int64) CacheOption {
+	return func(c *Cache) {
+		c.sizeOf = f
+	}
+}
+
+// WithStaleGrace оставляет просроченные записи в кэше ещё на d, чтобы их можно было
+// отдать через GetStale, пока значение обновляется в фоне
+func WithStaleGrace(d time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.staleGrace = d
+	}
+}
+
+// WithSweepInterval задаёт период фоновой очистки просроченных записей
+func WithSweepInterval(d time.Duration) CacheOption {
+	return func(c *Cache) {
+		c.sweepEvery = d
+	}
+}
+
+type cacheEntry struct {
+	key       string
+	value     interface{}
+	size      int64
+	setAt     time.Time
+	expiresAt time.Time
+}
+
+// Cache - кэш с TTL. Без опций он не ограничен по размеру, с WithMaxEntries/WithMaxBytes
+// вытесняет давно не использованные записи. Просроченные записи удаляет один фоновый sweeper.
 type Cache struct {
-	data  map[string]interface{}
-	mutex sync.RWMutex
+	data  map[string]*list.Element
+	order *list.List // в начале - самые свежие по использованию
+	mutex sync.Mutex
 	ttl   time.Duration
+
+	staleGrace time.Duration
+
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	sizeOf     func(key string, value interface{}) int64
+
+	sweepEvery time.Duration
+	stop       chan struct{}
+	stopOnce   sync.Once
+
+	hits        int64
+	misses      int64
+	staleHits   int64
+	evictions   int64
+	expirations int64
 }
 
-func NewCache(ttl time.Duration) *Cache {
-	return &Cache{
-		data: make(map[string]interface{}),
-		ttl:  ttl,
+func NewCache(ttl time.Duration, opts ...CacheOption) *Cache {
+	c := &Cache{
+		data:   make(map[string]*list.Element),
+		order:  list.New(),
+		ttl:    ttl,
+		sizeOf: estimateSize,
+		stop:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.sweepEvery <= 0 {
+		c.sweepEvery = defaultSweepInterval(ttl)
 	}
+	go c.sweeper()
+	return c
 }
 
 func (c *Cache) Set(key string, value interface{}) {
+	c.SetWithTTL(key, value, c.ttl)
+}
+
+// SetWithTTL сохраняет значение с собственным временем жизни
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	c.data[key] = value
-	time.AfterFunc(c.ttl, func() {
-		c.Remove(key)
-	})
+
+	var size int64
+	if c.maxBytes > 0 {
+		size = c.sizeOf(key, value)
+	}
+	now := time.Now()
+	c.storeEntry(&cacheEntry{key: key, value: value, size: size, setAt: now, expiresAt: now.Add(ttl)})
 }
 
-// Get получает значение из кэша по ключу
+// storeEntry кладёт запись в кэш и вытесняет лишнее. Вызывается под mutex.
+func (c *Cache) storeEntry(entry *cacheEntry) {
+	if el, ok := c.data[entry.key]; ok {
+		c.bytes -= el.Value.(*cacheEntry).size
+		el.Value = entry
+		c.order.MoveToFront(el)
+	} else {
+		c.data[entry.key] = c.order.PushFront(entry)
+	}
+	c.bytes += entry.size
+	c.evict()
+}
+
+// Get получает значение из кэша по ключу. Просроченное значение считается промахом.
 func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	value, exists := c.data[key]
-	return value, exists
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	value, ttl, found := c.lookup(key)
+	if !found || ttl <= 0 {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	return value, true
+}
+
+// GetStale получает значение по ключу, в том числе просроченное, но ещё не вышедшее
+// за окно WithStaleGrace. stale == true означает, что значение пора обновить.
+func (c *Cache) GetStale(key string) (value interface{}, stale bool, found bool) {
+	value, ttl, found := c.GetTTL(key)
+	return value, found && ttl <= 0, found
+}
+
+// GetTTL - GetStale с остатком времени жизни значения вместо признака устаревания.
+// Отданное просроченное значение учитывается в статистике отдельно от попаданий.
+func (c *Cache) GetTTL(key string) (value interface{}, ttl time.Duration, found bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	value, ttl, found = c.lookup(key)
+	switch {
+	case !found:
+		c.misses++
+	case ttl <= 0:
+		c.staleHits++
+	default:
+		c.hits++
+	}
+	return value, ttl, found
+}
+
+// lookup ищет запись без учёта в статистике. Вызывается под mutex.
+func (c *Cache) lookup(key string) (interface{}, time.Duration, bool) {
+	el, exists := c.data[key]
+	if !exists {
+		return nil, 0, false
+	}
+	entry := el.Value.(*cacheEntry)
+	now := time.Now()
+	if now.After(entry.expiresAt.Add(c.staleGrace)) {
+		c.removeElement(el)
+		c.expirations++
+		return nil, 0, false
+	}
+	c.order.MoveToFront(el)
+	return entry.value, entry.expiresAt.Sub(now), true
 }
 
 func (s *Server) Serve() {
 	log.Println("Starting server...")
 	if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Server error: &v", err)
+		log.Fatalf("Server error: %v", err)
 	}
 }
 
-func (c *Cache) Remove(key string) {
+// Remove удаляет запись и сообщает, была ли она
+func (c *Cache) Remove(key string) bool {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	delete(c.data, key)
+	el, ok := c.data[key]
+	if ok {
+		c.removeElement(el)
+	}
+	return ok
+}
+
+// Len возвращает количество записей в кэше, включая ещё не удалённые просроченные
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.data)
+}
+
+// Close останавливает фоновую очистку
+func (c *Cache) Close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// evict вытесняет самые старые по использованию записи, пока кэш не уложится в лимиты.
+// Вызывается под mutex.
+func (c *Cache) evict() {
+	for c.order.Len() > 0 &&
+		((c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes)) {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.order.Remove(el)
+	delete(c.data, entry.key)
+	c.bytes -= entry.size
+}
+
+func (c *Cache) sweeper() {
+	ticker := time.NewTicker(c.sweepEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.deleteExpired()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+func (c *Cache) deleteExpired() {
+	now := time.Now()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, el := range c.data {
+		if now.After(el.Value.(*cacheEntry).expiresAt.Add(c.staleGrace)) {
+			c.removeElement(el)
+			c.expirations++
+		}
+	}
+}
+
+func defaultSweepInterval(ttl time.Duration) time.Duration {
+	switch {
+	case ttl <= 0:
+		return time.Minute
+	case ttl < time.Second:
+		return time.Second
+	case ttl > time.Minute:
+		return time.Minute
+	}
+	return ttl
+}
+
+// estimateSize грубо оценивает размер записи по длине её JSON-представления
+func estimateSize(key string, value interface{}) int64 {
+	size := int64(len(key))
+	switch v := value.(type) {
+	case string:
+		return size + int64(len(v))
+	case []byte:
+		return size + int64(len(v))
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return size + 64
+	}
+	return size + int64(len(data))
 }
@@ -0,0 +1,82 @@
+package adapter
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// Keys возвращает отсортированный список ключей с заданным префиксом
+func (c *Cache) Keys(prefix string) []string {
+	c.mutex.Lock()
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	c.mutex.Unlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// Peek возвращает запись с её возрастом и остатком TTL, не трогая порядок LRU и статистику
+func (c *Cache) Peek(key string) (entity.CacheEntryInfo, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	el, ok := c.data[key]
+	if !ok {
+		return entity.CacheEntryInfo{}, false
+	}
+	return newEntryInfo(el.Value.(*cacheEntry), time.Now()), true
+}
+
+// RemovePrefix удаляет все записи с заданным префиксом и возвращает их количество
+func (c *Cache) RemovePrefix(prefix string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	removed := 0
+	for key, el := range c.data {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(el)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Flush очищает кэш целиком
+func (c *Cache) Flush() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.data = make(map[string]*list.Element)
+	c.order.Init()
+	c.bytes = 0
+}
+
+func (c *Cache) Stats() entity.CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return entity.CacheStats{
+		Entries:     len(c.data),
+		Bytes:       c.bytes,
+		Hits:        c.hits,
+		Misses:      c.misses,
+		StaleHits:   c.staleHits,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+	}
+}
+
+func newEntryInfo(entry *cacheEntry, now time.Time) entity.CacheEntryInfo {
+	return entity.CacheEntryInfo{
+		Key:        entry.key,
+		Value:      entry.value,
+		AgeSeconds: now.Sub(entry.setAt).Seconds(),
+		TTLSeconds: entry.expiresAt.Sub(now).Seconds(),
+		Stale:      now.After(entry.expiresAt),
+	}
+}
@@ -0,0 +1,173 @@
+package adapter
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// snapshotEntry - запись снимка кэша: ключ, gob-закодированный cacheEnvelope и время жизни,
+// оставшееся на момент записи. В старых снимках TTL нет, там срок берётся из ExpiresAt.
+type snapshotEntry struct {
+	Key  string
+	Data []byte
+	TTL  time.Duration
+}
+
+// Snapshot записывает содержимое кэша вместе с оставшимся временем жизни записей.
+// Время, пока снимок лежит на диске, не расходует TTL: снимок, подготовленный заранее
+// (например, cmd/warm перед стартом сервера), не устаревает к моменту загрузки.
+// Значения незарегистрированных типов (см. RegisterCacheType) пропускаются.
+func (c *Cache) Snapshot(w io.Writer) error {
+	c.mutex.Lock()
+	entries := make([]*cacheEntry, 0, c.order.Len())
+	// Идём от старых к свежим, чтобы при загрузке сохранился порядок LRU
+	for el := c.order.Back(); el != nil; el = el.Prev() {
+		entries = append(entries, el.Value.(*cacheEntry))
+	}
+	c.mutex.Unlock()
+
+	now := time.Now()
+	snapshot := make([]snapshotEntry, 0, len(entries))
+	for _, entry := range entries {
+		data, err := encodeEnvelope(cacheEnvelope{Value: entry.value, SetAt: entry.setAt, ExpiresAt: entry.expiresAt})
+		if err != nil {
+			log.Printf("cache snapshot: skip %q: %v", entry.key, err)
+			continue
+		}
+		snapshot = append(snapshot, snapshotEntry{Key: entry.key, Data: data, TTL: entry.expiresAt.Sub(now)})
+	}
+	return gob.NewEncoder(w).Encode(snapshot)
+}
+
+// Restore загружает снимок, пропуская записи, которые уже вышли за окно устаревания.
+// Возвращает количество загруженных записей.
+func (c *Cache) Restore(r io.Reader) (int, error) {
+	var snapshot []snapshotEntry
+	if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	loaded := 0
+	for _, item := range snapshot {
+		env, err := decodeEnvelope(item.Data)
+		if err != nil {
+			log.Printf("cache snapshot: skip %q: %v", item.Key, err)
+			continue
+		}
+		if item.TTL != 0 {
+			env.ExpiresAt = now.Add(item.TTL)
+		}
+		if now.After(env.ExpiresAt.Add(c.staleGrace)) {
+			continue
+		}
+		var size int64
+		if c.maxBytes > 0 {
+			size = c.sizeOf(item.Key, env.Value)
+		}
+		c.storeEntry(&cacheEntry{key: item.Key, value: env.Value, size: size, setAt: env.SetAt, expiresAt: env.ExpiresAt})
+		loaded++
+	}
+	return loaded, nil
+}
+
+// SaveFile атомарно записывает снимок в файл
+func (c *Cache) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := c.Snapshot(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile загружает снимок из файла. Отсутствие файла не считается ошибкой.
+func (c *Cache) LoadFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer file.Close()
+	return c.Restore(file)
+}
+
+// StartSnapshots периодически сохраняет снимок в файл, пока кэш не закрыт
+func (c *Cache) StartSnapshots(path string, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.SaveFile(path); err != nil {
+					log.Printf("cache snapshot: %v", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// SnapshotLockedError - файлом снимка уже владеет другой работающий процесс
+type SnapshotLockedError struct {
+	Path string
+	PID  int
+}
+
+func (e *SnapshotLockedError) Error() string {
+	return fmt.Sprintf("cache snapshot %s is owned by running process %d", e.Path, e.PID)
+}
+
+// LockSnapshot закрепляет файл снимка за текущим процессом: рядом создаётся <path>.lock,
+// на который берётся flock. Ядро снимает его вместе с процессом, поэтому замок после
+// SIGKILL не мешает перезапуску, даже если новый процесс получит тот же PID. Если снимком
+// владеет другой процесс, возвращает *SnapshotLockedError. unlock снимает замок.
+func LockSnapshot(path string) (unlock func(), err error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		defer f.Close()
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, err
+		}
+		data, _ := io.ReadAll(f)
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		return nil, &SnapshotLockedError{Path: path, PID: pid}
+	}
+	// PID владельца нужен только для сообщения об ошибке
+	if err := f.Truncate(0); err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	// Файл замка не удаляется: иначе процесс, открывший его до удаления, взял бы замок
+	// на уже удалённом файле одновременно с тем, кто создаст новый
+	return func() { f.Close() }, nil
+}
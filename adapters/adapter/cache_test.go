@@ -0,0 +1,168 @@
+package adapter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewCache(time.Minute, WithMaxEntries(2))
+	defer c.Close()
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // "b" становится самым старым
+	c.Set("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to stay in cache", key)
+		}
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	c := NewCache(time.Minute, WithMaxBytes(10))
+	defer c.Close()
+
+	c.Set("k1", "1234")
+	c.Set("k2", "1234")
+	if c.Len() != 1 {
+		t.Fatalf("expected 1 entry within byte budget, got %d", c.Len())
+	}
+	if _, ok := c.Get("k2"); !ok {
+		t.Error("expected newest entry to stay in cache")
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := NewCache(20*time.Millisecond, WithSweepInterval(10*time.Millisecond))
+	defer c.Close()
+
+	c.Set("key", "value")
+	if _, ok := c.Get("key"); !ok {
+		t.Fatal("expected fresh entry")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if c.Len() != 0 {
+		t.Errorf("expected sweeper to remove expired entry, got %d entries", c.Len())
+	}
+}
+
+func TestCacheGetStale(t *testing.T) {
+	c := NewCache(10*time.Millisecond, WithStaleGrace(time.Minute))
+	defer c.Close()
+
+	c.Set("key", "value")
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := c.Get("key"); ok {
+		t.Error("expected Get to skip expired entry")
+	}
+	value, stale, found := c.GetStale("key")
+	if !found || !stale || value != "value" {
+		t.Errorf("expected stale value, got %v stale=%v found=%v", value, stale, found)
+	}
+	// Просроченное значение не попадание: Get - промах, GetStale - отдельный счётчик
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 1 || stats.StaleHits != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCacheSnapshotRestore(t *testing.T) {
+	src := NewCache(time.Minute)
+	defer src.Close()
+	src.Set("search:тверская", entity.ResponseAddresses{Addresses: []*entity.Address{{City: "Москва", Street: "Тверская"}}})
+	src.SetWithTTL("expired", "value", -time.Second)
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewCache(time.Minute)
+	defer dst.Close()
+	loaded, err := dst.Restore(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != 1 {
+		t.Fatalf("expected 1 restored entry, got %d", loaded)
+	}
+	value, ok := dst.Get("search:тверская")
+	if !ok {
+		t.Fatal("expected restored entry")
+	}
+	geo, ok := value.(entity.ResponseAddresses)
+	if !ok || geo.Addresses[0].Street != "Тверская" {
+		t.Errorf("unexpected restored value %#v", value)
+	}
+}
+
+func TestCacheSnapshotKeepsRemainingTTL(t *testing.T) {
+	src := NewCache(100 * time.Millisecond)
+	defer src.Close()
+	src.Set("key", "value")
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пока снимок лежит на диске, время жизни записи не идёт
+	time.Sleep(150 * time.Millisecond)
+	dst := NewCache(time.Minute)
+	defer dst.Close()
+	if loaded, err := dst.Restore(&buf); err != nil || loaded != 1 {
+		t.Fatalf("expected 1 restored entry, got %d, %v", loaded, err)
+	}
+	if _, ttl, found := dst.GetTTL("key"); !found || ttl <= 0 || ttl > 100*time.Millisecond {
+		t.Errorf("expected the remaining ttl to be kept, got %v found=%v", ttl, found)
+	}
+}
+
+func TestLockSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+
+	unlock, err := LockSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locked *SnapshotLockedError
+	if _, err := LockSnapshot(path); !errors.As(err, &locked) || locked.PID != os.Getpid() {
+		t.Fatalf("expected SnapshotLockedError while the lock is held, got %v", err)
+	}
+	unlock()
+
+	// Файл замка процесса, убитого без unlock, без flock ничего не держит
+	if err := os.WriteFile(path+".lock", []byte("999999999"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = LockSnapshot(path)
+	if err != nil {
+		t.Fatalf("expected stale lock to be taken over, got %v", err)
+	}
+	unlock()
+}
+
+func TestLockSnapshotSamePID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snapshot")
+	// В контейнере процесс всегда PID 1: после SIGKILL в замке остаётся PID нового процесса
+	if err := os.WriteFile(path+".lock", []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := LockSnapshot(path)
+	if err != nil {
+		t.Fatalf("expected a leftover lock with our pid to be taken over, got %v", err)
+	}
+	unlock()
+}
@@ -0,0 +1,41 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func init() {
+	RegisterCacheType(entity.ResponseAddresses{})
+	RegisterCacheType(entity.CachedError{})
+}
+
+// RegisterCacheType регистрирует тип значения, чтобы внешние бэкенды кэша
+// восстанавливали его как есть, а не как interface{}
+func RegisterCacheType(value interface{}) {
+	gob.Register(value)
+}
+
+// cacheEnvelope - сериализуемая запись кэша вместе с её временем жизни
+type cacheEnvelope struct {
+	Value     interface{}
+	SetAt     time.Time
+	ExpiresAt time.Time
+}
+
+func encodeEnvelope(env cacheEnvelope) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&env); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeEnvelope(data []byte) (cacheEnvelope, error) {
+	var env cacheEnvelope
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&env)
+	return env, err
+}
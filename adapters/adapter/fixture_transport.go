@@ -0,0 +1,233 @@
+package adapter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNoFixture - для запроса нет записанной фикстуры
+var ErrNoFixture = errors.New("no recorded fixture for request")
+
+// Fixture - записанная пара запрос/ответ. Заголовки запроса не сохраняются,
+// чтобы ключи API не попадали в файлы.
+type Fixture struct {
+	Method string `json:"method"`
+	// Path включает строку запроса, если она была
+	Path        string          `json:"path"`
+	Body        json.RawMessage `json:"body,omitempty"`
+	Status      int             `json:"status"`
+	ContentType string          `json:"content_type,omitempty"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// fixtureKey - по нему сопоставляются запросы: метод, путь и нормализованное тело
+func fixtureKey(method, path string, body []byte) string {
+	return method + " " + path + " " + string(normalizeBody(body))
+}
+
+// fixturePath - путь к запросу без адреса сервера, чтобы фикстуры не зависели от base URL
+func fixturePath(req *http.Request) string {
+	path := req.URL.Path
+	if query := req.URL.Query().Encode(); query != "" {
+		path += "?" + query
+	}
+	return path
+}
+
+// normalizeBody убирает из JSON различия в пробелах, порядке ключей и записи чисел.
+// Не-JSON тело сравнивается как есть, без крайних пробелов.
+func normalizeBody(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// rawJSON сохраняет тело в фикстуре как JSON, а не-JSON - как строку JSON
+func rawJSON(body []byte) json.RawMessage {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// rawBody - обратное к rawJSON. Пустой contentType - тело запроса, у которого тип не сохраняется.
+func rawBody(raw json.RawMessage, contentType string) []byte {
+	if !strings.Contains(contentType, "json") {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return []byte(s)
+		}
+	}
+	return raw
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// RecordingTransport проксирует запросы в Next и сохраняет каждую пару запрос/ответ
+// в отдельный файл каталога Dir. Повторный такой же запрос перезаписывает фикстуру.
+type RecordingTransport struct {
+	Dir  string
+	Next http.RoundTripper
+}
+
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	fixture := Fixture{
+		Method:      req.Method,
+		Path:        fixturePath(req),
+		Body:        rawJSON(body),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Response:    rawJSON(respBody),
+	}
+	if err := t.save(fixture, body); err != nil {
+		return nil, fmt.Errorf("record fixture: %w", err)
+	}
+	return resp, nil
+}
+
+func (t *RecordingTransport) save(fixture Fixture, body []byte) error {
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(t.Dir, fixtureFileName(fixture, body)), append(data, '\n'), 0o644)
+}
+
+// fixtureFileName - читаемое имя из метода и пути плюс короткий хеш ключа
+func fixtureFileName(fixture Fixture, body []byte) string {
+	sum := sha256.Sum256([]byte(fixtureKey(fixture.Method, fixture.Path, body)))
+	path, _, _ := strings.Cut(fixture.Path, "?")
+	name := strings.Trim(strings.NewReplacer("/", "_", ".", "_").Replace(path), "_")
+	return strings.ToLower(fixture.Method) + "_" + name + "_" + hex.EncodeToString(sum[:4]) + ".json"
+}
+
+// ReplayTransport отвечает на запросы записанными фикстурами, не обращаясь к сети.
+// Запрос без фикстуры завершается ошибкой ErrNoFixture.
+type ReplayTransport struct {
+	mu       sync.Mutex
+	fixtures map[string]Fixture
+	misses   []string
+}
+
+// NewReplayTransport загружает все *.json из каталога dir
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	t := &ReplayTransport{fixtures: make(map[string]Fixture, len(files))}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", file, err)
+		}
+		t.fixtures[fixtureKey(fixture.Method, fixture.Path, rawBody(fixture.Body, ""))] = fixture
+	}
+	return t, nil
+}
+
+// Len возвращает количество загруженных фикстур
+func (t *ReplayTransport) Len() int {
+	return len(t.fixtures)
+}
+
+// Misses возвращает ключи запросов, для которых не нашлось фикстуры, - их удобно выводить в тестах
+func (t *ReplayTransport) Misses() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.misses...)
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := fixtureKey(req.Method, fixturePath(req), body)
+	fixture, ok := t.fixtures[key]
+	if !ok {
+		t.mu.Lock()
+		t.misses = append(t.misses, key)
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNoFixture, key)
+	}
+
+	header := make(http.Header)
+	if fixture.ContentType != "" {
+		header.Set("Content-Type", fixture.ContentType)
+	}
+	respBody := rawBody(fixture.Response, fixture.ContentType)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fixture.Status, http.StatusText(fixture.Status)),
+		StatusCode:    fixture.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package adapter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFixtureRecordReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"ok": true}`)
+	}))
+	defer srv.Close()
+	dir := t.TempDir()
+
+	recorder := &http.Client{Transport: &RecordingTransport{Dir: dir}}
+	req, _ := http.NewRequest("POST", srv.URL+"/api/geo", strings.NewReader(`{"lat": 55.75, "lon": 37.62}`))
+	req.Header.Set("Authorization", "Token secret")
+	resp, err := recorder.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 fixture, got %d", len(files))
+	}
+	data, _ := os.ReadFile(dir + "/" + files[0].Name())
+	if strings.Contains(string(data), "secret") {
+		t.Error("request headers must not be recorded")
+	}
+
+	replay, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: replay}
+	// Другой сервер, другой порядок ключей и пробелы - та же фикстура
+	resp, err = client.Post("http://replay.invalid/api/geo", "application/json", strings.NewReader(`{ "lon":37.620, "lat":55.75 }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	// JSON в фикстуре хранится с отступами, поэтому сравниваем нормализованное тело
+	if resp.StatusCode != http.StatusCreated || string(normalizeBody(body)) != `{"ok":true}` {
+		t.Errorf("unexpected replay %d %s", resp.StatusCode, body)
+	}
+
+	_, err = client.Post("http://replay.invalid/api/geo", "application/json", strings.NewReader(`{"lat": 1}`))
+	if !errors.Is(err, ErrNoFixture) {
+		t.Errorf("expected ErrNoFixture, got %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package adapter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// FileJobStore хранит задания в каталоге: <id>.json - состояние, <id>.input.ndjson - запросы,
+// <id>.results.ndjson - результаты, дописываемые по одному. Реализует entity.JobStore.
+type FileJobStore struct {
+	dir string
+	// mu защищает дописывание результатов: строки разных горутин не должны перемешаться
+	mu sync.Mutex
+}
+
+func NewFileJobStore(dir string) (*FileJobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileJobStore{dir: dir}, nil
+}
+
+func (s *FileJobStore) path(id, suffix string) string {
+	return filepath.Join(s.dir, id+suffix)
+}
+
+func (s *FileJobStore) SaveJob(job entity.Job) error {
+	data, err := json.Marshal(jobFile{Job: job, Owner: job.Owner})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(job.ID, ".json"), data)
+}
+
+// jobFile - entity.Job на диске; Owner в API не отдаётся, но сохраняться должен
+type jobFile struct {
+	entity.Job
+	Owner string `json:"owner"`
+}
+
+func (s *FileJobStore) ListJobs() ([]entity.Job, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]entity.Job, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f jobFile
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("job %s: %w", file, err)
+		}
+		f.Job.Owner = f.Owner
+		jobs = append(jobs, f.Job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+func (s *FileJobStore) SaveInput(id string, items []entity.JobItem) error {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(s.path(id, ".input.ndjson"), []byte(buf.String()))
+}
+
+func (s *FileJobStore) LoadInput(id string) ([]entity.JobItem, error) {
+	var items []entity.JobItem
+	err := readNDJSON(s.path(id, ".input.ndjson"), func(line []byte) error {
+		var item entity.JobItem
+		if err := json.Unmarshal(line, &item); err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+func (s *FileJobStore) AppendResult(id string, result entity.JobResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path(id, ".results.ndjson"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// EachResult читает результаты в порядке Index, не загружая их в память: сначала собирает
+// индекс строк файла, затем читает строки по смещениям. Оборванную строку, которую мог
+// оставить сбой посреди записи, пропускает: элемент просто выполнится ещё раз.
+func (s *FileJobStore) EachResult(id string, fn func(entity.JobResult) error) error {
+	f, err := os.Open(s.path(id, ".results.ndjson"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	type resultLine struct {
+		index  int
+		offset int64
+		size   int
+	}
+	var lines []resultLine
+	reader := bufio.NewReaderSize(f, 64*1024)
+	var offset int64
+	for {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			var head struct {
+				Index int `json:"index"`
+			}
+			if json.Unmarshal(data, &head) == nil {
+				lines = append(lines, resultLine{index: head.Index, offset: offset, size: len(data)})
+			}
+			offset += int64(len(data))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Стабильная сортировка сохраняет порядок записи, поэтому из повторов последний - самый свежий
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].index < lines[j].index
+	})
+	for i, line := range lines {
+		if i+1 < len(lines) && lines[i+1].index == line.index {
+			continue
+		}
+		data := make([]byte, line.size)
+		if _, err := f.ReadAt(data, line.offset); err != nil {
+			return err
+		}
+		var result entity.JobResult
+		if err := json.Unmarshal(data, &result); err != nil {
+			return err
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileJobStore) DeleteJob(id string) error {
+	for _, suffix := range []string{".results.ndjson", ".input.ndjson", ".json"} {
+		if err := os.Remove(s.path(id, suffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func readNDJSON(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
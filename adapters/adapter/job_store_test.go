@@ -0,0 +1,85 @@
+package adapter
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestFileJobStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := entity.Job{ID: "j1", Kind: entity.JobSearch, State: entity.JobQueued, Owner: "alice", Total: 2, CreatedAt: time.Now().UTC()}
+	if err := store.SaveJob(job); err != nil {
+		t.Fatal(err)
+	}
+	items := []entity.JobItem{{Query: "Москва"}, {Error: "line 3: bad"}}
+	if err := store.SaveInput(job.ID, items); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := collectResults(store, job.ID); err != nil || results != nil {
+		t.Fatalf("expected no results yet, got %v %v", results, err)
+	}
+	// Элемент 0 выполнен дважды: сбой между записью результата и сохранением прогресса
+	for _, result := range []entity.JobResult{
+		{Index: 1, Item: items[1], Error: items[1].Error},
+		{Index: 0, Item: items[0], Provider: "first"},
+		{Index: 0, Item: items[0], Provider: "second"},
+	} {
+		if err := store.AppendResult(job.ID, result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Сбой посреди записи оставляет оборванную строку
+	f, err := os.OpenFile(store.path(job.ID, ".results.ndjson"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"index":0,"item":{"que`)
+	f.Close()
+
+	reopened, _ := NewFileJobStore(dir)
+	jobs, err := reopened.ListJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Owner != "alice" || jobs[0].Total != 2 {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+	input, err := reopened.LoadInput(job.ID)
+	if err != nil || len(input) != 2 || input[0].Query != "Москва" || input[1].Error == "" {
+		t.Fatalf("unexpected input %+v %v", input, err)
+	}
+	results, err := collectResults(reopened, job.ID)
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected the truncated line to be skipped, got %+v %v", results, err)
+	}
+	if results[0].Index != 0 || results[0].Provider != "second" || results[1].Index != 1 {
+		t.Errorf("expected results by index with the last repeat, got %+v", results)
+	}
+
+	if err := reopened.DeleteJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected job files to be deleted, got %v", files)
+	}
+	if jobs, _ := reopened.ListJobs(); len(jobs) != 0 {
+		t.Errorf("expected no jobs after delete, got %+v", jobs)
+	}
+}
+
+func collectResults(store *FileJobStore, id string) ([]entity.JobResult, error) {
+	var results []entity.JobResult
+	err := store.EachResult(id, func(result entity.JobResult) error {
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,77 @@
+package adapter
+
+import (
+	"context"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// MemoryCache - реализация entity.Cache поверх in-process Cache
+type MemoryCache struct {
+	cache *Cache
+}
+
+var (
+	_ entity.StaleCache = (*MemoryCache)(nil)
+	_ entity.CacheAdmin = (*MemoryCache)(nil)
+)
+
+func NewMemoryCache(cache *Cache) *MemoryCache {
+	return &MemoryCache{cache: cache}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	value, found := m.cache.Get(key)
+	return value, found, nil
+}
+
+func (m *MemoryCache) GetStale(ctx context.Context, key string) (interface{}, bool, bool, error) {
+	value, stale, found := m.cache.GetStale(key)
+	return value, stale, found, nil
+}
+
+func (m *MemoryCache) GetTTL(ctx context.Context, key string) (interface{}, time.Duration, bool, error) {
+	value, ttl, found := m.cache.GetTTL(key)
+	return value, ttl, found, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		m.cache.Set(key, value)
+		return nil
+	}
+	m.cache.SetWithTTL(key, value, ttl)
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, key string) error {
+	m.cache.Remove(key)
+	return nil
+}
+
+func (m *MemoryCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return m.cache.Keys(prefix), nil
+}
+
+func (m *MemoryCache) Entry(ctx context.Context, key string) (entity.CacheEntryInfo, bool, error) {
+	info, found := m.cache.Peek(key)
+	return info, found, nil
+}
+
+func (m *MemoryCache) DeleteKey(ctx context.Context, key string) (bool, error) {
+	return m.cache.Remove(key), nil
+}
+
+func (m *MemoryCache) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	return m.cache.RemovePrefix(prefix), nil
+}
+
+func (m *MemoryCache) Flush(ctx context.Context) error {
+	m.cache.Flush()
+	return nil
+}
+
+func (m *MemoryCache) Stats(ctx context.Context) (entity.CacheStats, error) {
+	return m.cache.Stats(), nil
+}
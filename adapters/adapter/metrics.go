@@ -0,0 +1,58 @@
+package adapter
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Metrics - простой реестр счётчиков
+type Metrics struct {
+	mu       sync.RWMutex
+	counters map[string]*int64
+}
+
+// DefaultMetrics - общий реестр счётчиков приложения
+var DefaultMetrics = NewMetrics()
+
+func NewMetrics() *Metrics {
+	return &Metrics{counters: make(map[string]*int64)}
+}
+
+func (m *Metrics) Inc(name string) {
+	m.Add(name, 1)
+}
+
+func (m *Metrics) Add(name string, delta int64) {
+	m.mu.RLock()
+	counter, ok := m.counters[name]
+	m.mu.RUnlock()
+	if !ok {
+		m.mu.Lock()
+		if counter, ok = m.counters[name]; !ok {
+			counter = new(int64)
+			m.counters[name] = counter
+		}
+		m.mu.Unlock()
+	}
+	atomic.AddInt64(counter, delta)
+}
+
+func (m *Metrics) Get(name string) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if counter, ok := m.counters[name]; ok {
+		return atomic.LoadInt64(counter)
+	}
+	return 0
+}
+
+// Snapshot возвращает текущие значения всех счётчиков
+func (m *Metrics) Snapshot() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	res := make(map[string]int64, len(m.counters))
+	for name, counter := range m.counters {
+		res[name] = atomic.LoadInt64(counter)
+	}
+	return res
+}
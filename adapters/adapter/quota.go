@@ -0,0 +1,184 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// quotaSaveInterval - как часто изменившийся счётчик квоты сбрасывается в файл
+const quotaSaveInterval = 5 * time.Second
+
+// DailyQuota считает вызовы за сутки и сохраняет счётчик в файл, чтобы он переживал перезапуск.
+// Файл пишется раз в quotaSaveInterval и в Close, а не на каждый вызов: при аварийном
+// завершении теряется не больше нескольких секунд вызовов.
+// Сутки начинаются в полночь по location (у DaData - по Москве).
+type DailyQuota struct {
+	mu       sync.Mutex
+	limit    int
+	used     int
+	day      string
+	dirty    bool
+	path     string
+	location *time.Location
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+type quotaFile struct {
+	Day  string `json:"day"`
+	Used int    `json:"used"`
+}
+
+// NewDailyQuota загружает счётчик из path. Пустой path - счётчик только в памяти,
+// отсутствующий файл - начало с нуля.
+func NewDailyQuota(limit int, path string, location *time.Location) (*DailyQuota, error) {
+	if location == nil {
+		location = time.Local
+	}
+	q := &DailyQuota{limit: limit, path: path, location: location}
+	q.day = q.today()
+	if path == "" {
+		return q, nil
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		var saved quotaFile
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, err
+		}
+		if saved.Day == q.day {
+			q.used = saved.Used
+		}
+	}
+	q.startSaving()
+	return q, nil
+}
+
+// startSaving запускает периодическое сохранение счётчика до Close
+func (q *DailyQuota) startSaving() {
+	q.stop = make(chan struct{})
+	q.done = make(chan struct{})
+	go func() {
+		defer close(q.done)
+		ticker := time.NewTicker(quotaSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := q.Flush(); err != nil {
+					log.Printf("save quota: %v", err)
+				}
+			case <-q.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Take списывает один вызов. false - квота на сегодня исчерпана.
+func (q *DailyQuota) Take() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	if q.used >= q.limit {
+		return false
+	}
+	q.used++
+	q.dirty = true
+	return true
+}
+
+// Flush сохраняет счётчик, если он изменился с прошлого сохранения
+func (q *DailyQuota) Flush() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.dirty {
+		return nil
+	}
+	if err := q.save(); err != nil {
+		return err
+	}
+	q.dirty = false
+	return nil
+}
+
+// Close останавливает периодическое сохранение и сохраняет счётчик
+func (q *DailyQuota) Close() error {
+	if q.stop != nil {
+		q.stopOnce.Do(func() { close(q.stop) })
+		<-q.done
+	}
+	return q.Flush()
+}
+
+// Remaining возвращает остаток квоты на сегодня
+func (q *DailyQuota) Remaining() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	return q.limit - q.used
+}
+
+// Status возвращает лимит, использованные вызовы и момент сброса счётчика
+func (q *DailyQuota) Status() (limit, used int, resetAt time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.rollover()
+	now := time.Now().In(q.location)
+	resetAt = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, q.location)
+	return q.limit, q.used, resetAt
+}
+
+func (q *DailyQuota) today() string {
+	return time.Now().In(q.location).Format("2006-01-02")
+}
+
+// rollover обнуляет счётчик в новые сутки. Вызывается под mu.
+func (q *DailyQuota) rollover() {
+	if day := q.today(); day != q.day {
+		q.day = day
+		q.used = 0
+		q.dirty = true
+	}
+}
+
+// save атомарно перезаписывает файл счётчика. Вызывается под mu.
+func (q *DailyQuota) save() error {
+	if q.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(quotaFile{Day: q.day, Used: q.used})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(q.path, data)
+}
+
+// writeFileAtomic пишет файл через временный файл и rename, чтобы при сбое
+// на диске осталась старая или новая версия, но не обрезанная
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
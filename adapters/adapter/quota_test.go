@@ -0,0 +1,69 @@
+package adapter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDailyQuotaPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	q, err := NewDailyQuota(2, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Take() {
+		t.Fatal("expected first call to pass")
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, err := NewDailyQuota(2, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.Remaining(); got != 1 {
+		t.Fatalf("expected 1 call left after restart, got %d", got)
+	}
+	defer restarted.Close()
+	restarted.Take()
+	if restarted.Take() {
+		t.Error("expected quota to be exhausted")
+	}
+}
+
+func TestDailyQuotaRollover(t *testing.T) {
+	q, _ := NewDailyQuota(1, "", nil)
+	q.Take()
+	q.day = "2000-01-01"
+	if got := q.Remaining(); got != 1 {
+		t.Errorf("expected quota to reset on a new day, got %d left", got)
+	}
+}
+
+func TestDailyQuotaSavesInBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	q, err := NewDailyQuota(10, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+
+	q.Take()
+	q.Take()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected Take not to write the file, got %v", err)
+	}
+	if err := q.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	restarted, err := NewDailyQuota(10, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+	if got := restarted.Remaining(); got != 8 {
+		t.Errorf("expected 8 calls left after flush, got %d", got)
+	}
+}
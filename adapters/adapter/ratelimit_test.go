@@ -0,0 +1,41 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	b := NewTokenBucket(1, 3)
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatalf("expected token %d to be allowed", i)
+		}
+	}
+	if b.Allow() {
+		t.Error("expected bucket to be empty after burst")
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	b := NewTokenBucket(100, 1)
+	b.Allow()
+
+	start := time.Now()
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("expected to wait for a token, waited %v", elapsed)
+	}
+
+	// Токена не будет раньше дедлайна - ждать бессмысленно
+	slow := NewTokenBucket(0.1, 1)
+	slow.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := slow.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
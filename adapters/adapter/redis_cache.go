@@ -0,0 +1,488 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// RedisOption настраивает RedisCache
+type RedisOption func(*RedisCache)
+
+func WithRedisPassword(password string) RedisOption {
+	return func(c *RedisCache) {
+		c.password = password
+	}
+}
+
+func WithRedisDB(db int) RedisOption {
+	return func(c *RedisCache) {
+		c.db = db
+	}
+}
+
+// WithRedisKeyPrefix задаёт пространство ключей кэша в базе. Keys, DeletePrefix, Flush и Stats
+// видят только ключи с этим префиксом, поэтому базу можно делить с другими приложениями.
+func WithRedisKeyPrefix(prefix string) RedisOption {
+	return func(c *RedisCache) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithRedisTTL задаёт время жизни записей по умолчанию
+func WithRedisTTL(ttl time.Duration) RedisOption {
+	return func(c *RedisCache) {
+		c.ttl = ttl
+	}
+}
+
+// WithRedisStaleGrace - аналог WithStaleGrace для Redis
+func WithRedisStaleGrace(d time.Duration) RedisOption {
+	return func(c *RedisCache) {
+		c.staleGrace = d
+	}
+}
+
+func WithRedisPoolSize(n int) RedisOption {
+	return func(c *RedisCache) {
+		c.pool = make(chan *respConn, n)
+	}
+}
+
+// WithRedisTimeout задаёт таймаут подключения и операций, если в контексте нет дедлайна
+func WithRedisTimeout(d time.Duration) RedisOption {
+	return func(c *RedisCache) {
+		c.timeout = d
+	}
+}
+
+// DefaultRedisKeyPrefix - пространство ключей кэша по умолчанию
+const DefaultRedisKeyPrefix = "geo:"
+
+// RedisCache - реализация entity.Cache поверх сервера, говорящего по протоколу Redis (RESP).
+// Значения сериализуются через gob, поэтому их типы нужно регистрировать RegisterCacheType.
+type RedisCache struct {
+	addr       string
+	password   string
+	db         int
+	keyPrefix  string
+	ttl        time.Duration
+	staleGrace time.Duration
+	timeout    time.Duration
+	pool       chan *respConn
+
+	hits      int64
+	misses    int64
+	staleHits int64
+}
+
+var (
+	_ entity.StaleCache    = (*RedisCache)(nil)
+	_ entity.ExpiringCache = (*RedisCache)(nil)
+	_ entity.CacheAdmin    = (*RedisCache)(nil)
+	_ entity.CachePubSub   = (*RedisCache)(nil)
+)
+
+// respError - ошибка, которую вернул сам сервер
+type respError string
+
+func (e respError) Error() string {
+	return "redis: " + string(e)
+}
+
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func NewRedisCache(addr string, opts ...RedisOption) *RedisCache {
+	c := &RedisCache{
+		addr:      addr,
+		keyPrefix: DefaultRedisKeyPrefix,
+		ttl:       5 * time.Minute,
+		timeout:   3 * time.Second,
+		pool:      make(chan *respConn, 8),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	env, found, err := c.getEnvelope(ctx, key)
+	if err != nil || !found || !time.Now().Before(env.ExpiresAt) {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, false, err
+	}
+	atomic.AddInt64(&c.hits, 1)
+	return env.Value, true, nil
+}
+
+func (c *RedisCache) GetStale(ctx context.Context, key string) (interface{}, bool, bool, error) {
+	value, ttl, found, err := c.GetTTL(ctx, key)
+	return value, found && ttl <= 0, found, err
+}
+
+func (c *RedisCache) GetTTL(ctx context.Context, key string) (interface{}, time.Duration, bool, error) {
+	env, found, err := c.getEnvelope(ctx, key)
+	if err != nil || !found {
+		atomic.AddInt64(&c.misses, 1)
+		return nil, 0, false, err
+	}
+	ttl := time.Until(env.ExpiresAt)
+	if ttl <= 0 {
+		atomic.AddInt64(&c.staleHits, 1)
+	} else {
+		atomic.AddInt64(&c.hits, 1)
+	}
+	return env.Value, ttl, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = c.ttl
+	}
+	now := time.Now()
+	data, err := encodeEnvelope(cacheEnvelope{Value: value, SetAt: now, ExpiresAt: now.Add(ttl)})
+	if err != nil {
+		return err
+	}
+	px := strconv.FormatInt((ttl + c.staleGrace).Milliseconds(), 10)
+	_, err = c.Do(ctx, "SET", c.keyPrefix+key, string(data), "PX", px)
+	return err
+}
+
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	_, err := c.DeleteKey(ctx, key)
+	return err
+}
+
+func (c *RedisCache) DeleteKey(ctx context.Context, key string) (bool, error) {
+	reply, err := c.Do(ctx, "DEL", c.keyPrefix+key)
+	n, _ := reply.(int64)
+	return n > 0, err
+}
+
+// Keys перебирает ключи через SCAN, не блокируя сервер
+func (c *RedisCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := c.scan(ctx, prefix, func(batch []string) error {
+		for _, key := range batch {
+			keys = append(keys, strings.TrimPrefix(key, c.keyPrefix))
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+func (c *RedisCache) Entry(ctx context.Context, key string) (entity.CacheEntryInfo, bool, error) {
+	env, found, err := c.getEnvelope(ctx, key)
+	if err != nil || !found {
+		return entity.CacheEntryInfo{}, false, err
+	}
+	now := time.Now()
+	return entity.CacheEntryInfo{
+		Key:        key,
+		Value:      env.Value,
+		AgeSeconds: now.Sub(env.SetAt).Seconds(),
+		TTLSeconds: env.ExpiresAt.Sub(now).Seconds(),
+		Stale:      now.After(env.ExpiresAt),
+	}, true, nil
+}
+
+func (c *RedisCache) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	deleted := 0
+	err := c.scan(ctx, prefix, func(batch []string) error {
+		reply, err := c.Do(ctx, append([]string{"DEL"}, batch...)...)
+		if n, ok := reply.(int64); ok {
+			deleted += int(n)
+		}
+		return err
+	})
+	return deleted, err
+}
+
+// Flush удаляет по одному все ключи пространства WithRedisKeyPrefix, не трогая FLUSHDB
+func (c *RedisCache) Flush(ctx context.Context) error {
+	_, err := c.DeletePrefix(ctx, "")
+	return err
+}
+
+// Stats возвращает количество ключей кэша и попадания/промахи этого клиента.
+// Вытеснения и истечения считает сам сервер, здесь они не видны.
+func (c *RedisCache) Stats(ctx context.Context) (entity.CacheStats, error) {
+	stats := entity.CacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		StaleHits: atomic.LoadInt64(&c.staleHits),
+	}
+	if c.keyPrefix == "" {
+		reply, err := c.Do(ctx, "DBSIZE")
+		if n, ok := reply.(int64); ok {
+			stats.Entries = int(n)
+		}
+		return stats, err
+	}
+	err := c.scan(ctx, "", func(batch []string) error {
+		stats.Entries += len(batch)
+		return nil
+	})
+	return stats, err
+}
+
+// scan перебирает полные ключи базы в пространстве кэша с префиксом prefix
+func (c *RedisCache) scan(ctx context.Context, prefix string, fn func(keys []string) error) error {
+	pattern := globEscaper.Replace(c.keyPrefix+prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]interface{})
+		if !ok || len(items) != 2 {
+			return fmt.Errorf("redis: unexpected SCAN reply %v", reply)
+		}
+		next, _ := items[0].([]byte)
+		batch, _ := items[1].([]interface{})
+		keys := make([]string, 0, len(batch))
+		for _, key := range batch {
+			if b, ok := key.([]byte); ok {
+				keys = append(keys, string(b))
+			}
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		cursor = string(next)
+		if cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Ping проверяет доступность сервера
+func (c *RedisCache) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// Close закрывает простаивающие соединения
+func (c *RedisCache) Close() error {
+	for {
+		select {
+		case rc := <-c.pool:
+			rc.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// Publish отправляет сообщение в канал. К имени канала добавляется префикс ключей,
+// чтобы разные пространства ключей не получали чужие сообщения.
+func (c *RedisCache) Publish(ctx context.Context, channel, message string) error {
+	_, err := c.Do(ctx, "PUBLISH", c.keyPrefix+channel, message)
+	return err
+}
+
+// Subscribe вызывает fn для каждого сообщения из канала, пока не отменят ctx или не оборвётся
+// соединение. Подписка занимает отдельное соединение вне пула.
+func (c *RedisCache) Subscribe(ctx context.Context, channel string, fn func(message string)) error {
+	rc, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer rc.conn.Close()
+	stop := context.AfterFunc(ctx, func() { rc.conn.Close() })
+	defer stop()
+
+	if _, err := rc.do("SUBSCRIBE", c.keyPrefix+channel); err != nil {
+		return err
+	}
+	// Сообщений может не быть сколько угодно долго
+	rc.conn.SetDeadline(time.Time{})
+	for {
+		reply, err := readReply(rc.r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		// Сообщение - массив ["message", канал, данные]
+		items, _ := reply.([]interface{})
+		if len(items) != 3 {
+			continue
+		}
+		if kind, _ := items[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		data, _ := items[2].([]byte)
+		fn(string(data))
+	}
+}
+
+func (c *RedisCache) getEnvelope(ctx context.Context, key string) (cacheEnvelope, bool, error) {
+	reply, err := c.Do(ctx, "GET", c.keyPrefix+key)
+	if err != nil {
+		return cacheEnvelope{}, false, err
+	}
+	data, ok := reply.([]byte)
+	if !ok || data == nil {
+		return cacheEnvelope{}, false, nil
+	}
+	env, err := decodeEnvelope(data)
+	if err != nil {
+		return cacheEnvelope{}, false, fmt.Errorf("redis: decode %q: %w", key, err)
+	}
+	return env, true, nil
+}
+
+// Do отправляет команду и возвращает ответ: string, int64, []byte (nil для отсутствующего
+// значения), []interface{} или respError
+func (c *RedisCache) Do(ctx context.Context, args ...string) (interface{}, error) {
+	rc, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		rc.conn.SetDeadline(deadline)
+	} else {
+		rc.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	reply, err := rc.do(args...)
+	var serverErr respError
+	if err != nil && !errors.As(err, &serverErr) {
+		rc.conn.Close()
+		return nil, err
+	}
+	c.release(rc)
+	return reply, err
+}
+
+func (c *RedisCache) conn(ctx context.Context) (*respConn, error) {
+	select {
+	case rc := <-c.pool:
+		return rc, nil
+	default:
+	}
+	return c.dial(ctx)
+}
+
+// dial открывает новое соединение с авторизацией и выбором базы
+func (c *RedisCache) dial(ctx context.Context) (*respConn, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	rc := &respConn{conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	conn.SetDeadline(time.Now().Add(c.timeout))
+	if c.password != "" {
+		if _, err := rc.do("AUTH", c.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(c.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (c *RedisCache) release(rc *respConn) {
+	select {
+	case c.pool <- rc:
+	default:
+		rc.conn.Close()
+	}
+}
+
+func (rc *respConn) do(args ...string) (interface{}, error) {
+	fmt.Fprintf(rc.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(rc.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if err := rc.w.Flush(); err != nil {
+		return nil, err
+	}
+	reply, err := readReply(rc.r)
+	if err != nil {
+		return nil, err
+	}
+	if serverErr, ok := reply.(respError); ok {
+		return nil, serverErr
+	}
+	return reply, nil
+}
+
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return respError(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return []byte(nil), nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+}
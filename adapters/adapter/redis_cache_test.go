@@ -0,0 +1,255 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// respServer - минимальная in-process замена Redis для тестов: GET, SET [PX], DEL, SCAN MATCH, PING,
+// PUBLISH и SUBSCRIBE на один канал
+type respServer struct {
+	ln          net.Listener
+	mu          sync.Mutex
+	data        map[string]string
+	expires     map[string]time.Time
+	subscribers map[string][]net.Conn
+}
+
+func newRESPServer(t *testing.T) *respServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &respServer{ln: ln, data: make(map[string]string), expires: make(map[string]time.Time),
+		subscribers: make(map[string][]net.Conn)}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *respServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *respServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *respServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i] = string(item.([]byte))
+		}
+		if strings.ToUpper(args[0]) == "SUBSCRIBE" {
+			s.mu.Lock()
+			s.subscribers[args[1]] = append(s.subscribers[args[1]], conn)
+			s.mu.Unlock()
+			conn.Write([]byte("*3\r\n$9\r\nsubscribe\r\n" + bulk(args[1]) + ":1\r\n"))
+			continue
+		}
+		conn.Write([]byte(s.exec(args)))
+	}
+}
+
+func (s *respServer) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "PUBLISH":
+		message := "*3\r\n$7\r\nmessage\r\n" + bulk(args[1]) + bulk(args[2])
+		for _, conn := range s.subscribers[args[1]] {
+			conn.Write([]byte(message))
+		}
+		return ":" + strconv.Itoa(len(s.subscribers[args[1]])) + "\r\n"
+	case "GET":
+		value, ok := s.data[args[1]]
+		if exp, has := s.expires[args[1]]; has && time.Now().After(exp) {
+			ok = false
+		}
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+	case "SET":
+		s.data[args[1]] = args[2]
+		delete(s.expires, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.data[key]; ok {
+				delete(s.data, key)
+				deleted++
+			}
+		}
+		return ":" + strconv.Itoa(deleted) + "\r\n"
+	case "SCAN":
+		// Все ключи за один проход; поддерживается только MATCH <префикс>*
+		prefix := globUnescaper.Replace(strings.TrimSuffix(args[3], "*"))
+		var keys []string
+		for key := range s.data {
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, "$"+strconv.Itoa(len(key))+"\r\n"+key+"\r\n")
+			}
+		}
+		return "*2\r\n$1\r\n0\r\n*" + strconv.Itoa(len(keys)) + "\r\n" + strings.Join(keys, "")
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// waitSubscribers ждёт, пока на канал подпишутся n соединений
+func (s *respServer) waitSubscribers(t *testing.T, channel string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		got := len(s.subscribers[channel])
+		s.mu.Unlock()
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers on %q, got %d", n, channel, got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+var globUnescaper = strings.NewReplacer(`\\`, `\`, `\*`, "*", `\?`, "?", `\[`, "[", `\]`, "]")
+
+func TestRedisCacheRoundTrip(t *testing.T) {
+	srv := newRESPServer(t)
+	c := NewRedisCache(srv.addr())
+	defer c.Close()
+	ctx := context.Background()
+
+	want := entity.ResponseAddresses{Addresses: []*entity.Address{{City: "Москва", Street: "Тверская", House: "1"}}}
+	if err := c.Set(ctx, "search:тверская", want, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, found, err := c.Get(ctx, "search:тверская")
+	if err != nil || !found {
+		t.Fatalf("expected value, got found=%v err=%v", found, err)
+	}
+	geo, ok := got.(entity.ResponseAddresses)
+	if !ok {
+		t.Fatalf("expected entity.ResponseAddresses, got %T", got)
+	}
+	if len(geo.Addresses) != 1 || geo.Addresses[0].Street != "Тверская" {
+		t.Errorf("unexpected value %+v", geo)
+	}
+
+	if err := c.Delete(ctx, "search:тверская"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := c.Get(ctx, "search:тверская"); found {
+		t.Error("expected key to be deleted")
+	}
+}
+
+func TestRedisCacheStale(t *testing.T) {
+	srv := newRESPServer(t)
+	c := NewRedisCache(srv.addr(), WithRedisStaleGrace(time.Minute))
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "key", "value", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, found, _ := c.Get(ctx, "key"); found {
+		t.Error("expected Get to skip expired value")
+	}
+	value, stale, found, err := c.GetStale(ctx, "key")
+	if err != nil || !found || !stale || value != "value" {
+		t.Errorf("expected stale value, got %v stale=%v found=%v err=%v", value, stale, found, err)
+	}
+}
+
+func TestRedisCacheServerError(t *testing.T) {
+	srv := newRESPServer(t)
+	c := NewRedisCache(srv.addr())
+	defer c.Close()
+
+	if _, err := c.Do(context.Background(), "FLUSHALL"); err == nil {
+		t.Fatal("expected server error")
+	}
+	if err := c.Ping(context.Background()); err != nil {
+		t.Errorf("expected connection to stay usable, got %v", err)
+	}
+}
+
+func TestRedisCacheKeyPrefix(t *testing.T) {
+	srv := newRESPServer(t)
+	c := NewRedisCache(srv.addr(), WithRedisKeyPrefix("geo:"))
+	defer c.Close()
+	ctx := context.Background()
+
+	// Чужой ключ в той же базе
+	if _, err := c.Do(ctx, "SET", "session:1", "x"); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"search:a", "search:b", "geocode:c"} {
+		if err := c.Set(ctx, key, "value", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := c.Keys(ctx, "search:")
+	if err != nil || strings.Join(keys, ",") != "search:a,search:b" {
+		t.Errorf("unexpected keys %v %v", keys, err)
+	}
+	if stats, _ := c.Stats(ctx); stats.Entries != 3 {
+		t.Errorf("expected 3 cache entries, got %d", stats.Entries)
+	}
+	if found, err := c.DeleteKey(ctx, "search:a"); !found || err != nil {
+		t.Errorf("expected search:a to be deleted, got %v %v", found, err)
+	}
+	if found, _ := c.DeleteKey(ctx, "search:a"); found {
+		t.Error("expected a missing key not to be reported as deleted")
+	}
+
+	if err := c.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := c.Keys(ctx, ""); len(keys) != 0 {
+		t.Errorf("expected flush to remove cache keys, got %v", keys)
+	}
+	if value, _ := c.Do(ctx, "GET", "session:1"); string(value.([]byte)) != "x" {
+		t.Error("expected flush to keep keys outside the prefix")
+	}
+}
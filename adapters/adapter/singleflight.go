@@ -0,0 +1,104 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError - паника в fn, отданная ожидающим как ошибка. fn выполняется в отдельной
+// горутине, куда не дотягивается Recoverer роутера, и паника там уронила бы весь процесс.
+// Текст ошибки может уйти клиенту, поэтому стек в него не входит и пишется только в лог.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: panic: %v", e.Value)
+}
+
+type flightCall struct {
+	done    chan struct{}
+	val     interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// SingleFlight объединяет одновременные вызовы с одинаковым ключом в один
+type SingleFlight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+func NewSingleFlight() *SingleFlight {
+	return &SingleFlight{calls: make(map[string]*flightCall)}
+}
+
+// Do выполняет fn, если для key нет вызова в полёте, иначе ждёт уже идущий вызов.
+// shared == true означает, что результат получен от чужого вызова.
+func (g *SingleFlight) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.DoContext(context.Background(), key, func(context.Context) (interface{}, error) {
+		return fn()
+	})
+}
+
+// DoContext - Do с отменой. Ожидающий с отменённым ctx сразу получает ctx.Err(), а сам вызов
+// отменяется, только когда его перестали ждать все. Поэтому fn получает контекст со значениями
+// первого ctx, но без его дедлайна: дедлайн вызова fn задаёт сама.
+func (g *SingleFlight) DoContext(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		g.mu.Unlock()
+		return g.wait(ctx, key, c, true)
+	}
+	callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &flightCall{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr := &PanicError{Value: r, Stack: debug.Stack()}
+				log.Printf("%v\n\n%s", panicErr, panicErr.Stack)
+				c.val, c.err = nil, panicErr
+			}
+			cancel()
+			g.mu.Lock()
+			g.forget(key, c)
+			g.mu.Unlock()
+			close(c.done)
+		}()
+		c.val, c.err = fn(callCtx)
+	}()
+	return g.wait(ctx, key, c, false)
+}
+
+func (g *SingleFlight) wait(ctx context.Context, key string, c *flightCall, shared bool) (interface{}, error, bool) {
+	select {
+	case <-c.done:
+		return c.val, c.err, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			// Результат больше никому не нужен: отменяем вызов, а новые запросы начнут свой
+			c.cancel()
+			g.forget(key, c)
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err(), shared
+	}
+}
+
+// forget убирает вызов из карты, если его ещё не заменили новым. Вызывается под mu.
+func (g *SingleFlight) forget(key string, c *flightCall) {
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+}
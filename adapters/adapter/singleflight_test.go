@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleFlightDeduplicates(t *testing.T) {
+	g := NewSingleFlight()
+	var calls, shared int32
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, isShared := g.Do("key", func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "value", nil
+			})
+			if err != nil || v != "value" {
+				t.Errorf("unexpected result %v, %v", v, err)
+			}
+			if isShared {
+				atomic.AddInt32(&shared, 1)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+	if shared != 9 {
+		t.Errorf("expected 9 shared results, got %d", shared)
+	}
+}
+
+func TestSingleFlightCancelsWhenAllWaitersLeave(t *testing.T) {
+	g := NewSingleFlight()
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	second, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, err, _ := g.DoContext(first, "key", fn)
+		errs <- err
+	}()
+	<-started
+	go func() {
+		_, err, _ := g.DoContext(second, "key", fn)
+		errs <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancelFirst()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("expected canceled waiter to get context.Canceled, got %v", err)
+	}
+	select {
+	case <-canceled:
+		t.Fatal("call must keep running while someone still waits")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelSecond()
+	<-errs
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("expected call to be canceled after all waiters left")
+	}
+}
+
+func TestSingleFlightRecoversPanic(t *testing.T) {
+	g := NewSingleFlight()
+
+	_, err, _ := g.Do("key", func() (interface{}, error) {
+		panic("boom")
+	})
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("expected PanicError, got %v", err)
+	}
+	if strings.Contains(err.Error(), "goroutine") {
+		t.Errorf("expected error text without stack, got %q", err.Error())
+	}
+
+	// Ключ освобождён: следующий вызов выполняется заново
+	v, err, _ := g.Do("key", func() (interface{}, error) {
+		return "value", nil
+	})
+	if err != nil || v != "value" {
+		t.Errorf("unexpected result after panic %v, %v", v, err)
+	}
+}
@@ -0,0 +1,250 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// TieredCache - двухуровневый кэш: небольшой in-process L1 на Cache и общий для реплик L2.
+// Чтение идёт сначала в L1, при промахе в L2 с поднятием найденного значения в L1,
+// запись идёт в оба уровня.
+//
+// Если L2 умеет рассылать сообщения (entity.CachePubSub), удаление ключей и очистка
+// рассылаются остальным репликам, и те сбрасывают свой L1. Иначе удалённое значение
+// остаётся в L1 других реплик до конца его TTL.
+type TieredCache struct {
+	l1   *Cache
+	l2   entity.Cache
+	stop context.CancelFunc
+}
+
+// invalidationChannel - канал, в котором реплики сообщают об удалённых ключах.
+// Сообщения: "key:<ключ>", "prefix:<префикс>" и "flush".
+const invalidationChannel = "invalidate"
+
+var (
+	_ entity.StaleCache       = (*TieredCache)(nil)
+	_ entity.CacheAdmin       = (*TieredCache)(nil)
+	_ entity.TieredCacheStats = (*TieredCache)(nil)
+)
+
+// NewTieredCache собирает кэш из L1 и L2. TTL записей в L1 ограничен TTL самого l1,
+// поэтому для L1 обычно берут короткий TTL и WithMaxEntries.
+func NewTieredCache(l1 *Cache, l2 entity.Cache) *TieredCache {
+	t := &TieredCache{l1: l1, l2: l2}
+	if pubsub, ok := l2.(entity.CachePubSub); ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.stop = cancel
+		go t.listen(ctx, pubsub)
+	}
+	return t
+}
+
+// Close останавливает подписку на инвалидации; L1 и L2 закрывает их владелец
+func (t *TieredCache) Close() {
+	if t.stop != nil {
+		t.stop()
+	}
+}
+
+// listen применяет инвалидации других реплик к L1 и переподписывается при обрыве
+func (t *TieredCache) listen(ctx context.Context, pubsub entity.CachePubSub) {
+	for {
+		err := pubsub.Subscribe(ctx, invalidationChannel, t.invalidate)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("tiered cache: invalidation subscription: %v", err)
+		DefaultMetrics.Inc("cache.invalidation.errors")
+		// Пока подписки нет, сообщения теряются: L1 мог сохранить удалённые значения
+		t.l1.Flush()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (t *TieredCache) invalidate(message string) {
+	switch kind, arg, _ := strings.Cut(message, ":"); kind {
+	case "key":
+		t.l1.Remove(arg)
+	case "prefix":
+		t.l1.RemovePrefix(arg)
+	case "flush":
+		t.l1.Flush()
+	}
+}
+
+// publish рассылает инвалидацию после удаления из L2, чтобы реплики не подняли в L1
+// старое значение. Ошибка рассылки не отменяет удаление: у других реплик значение
+// доживёт в L1 до конца TTL, поэтому она только учитывается в метриках.
+func (t *TieredCache) publish(ctx context.Context, message string) {
+	pubsub, ok := t.l2.(entity.CachePubSub)
+	if !ok {
+		return
+	}
+	if err := pubsub.Publish(ctx, invalidationChannel, message); err != nil {
+		log.Printf("tiered cache: publish invalidation: %v", err)
+		DefaultMetrics.Inc("cache.invalidation.errors")
+	}
+}
+
+func (t *TieredCache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	value, stale, found, err := t.GetStale(ctx, key)
+	if stale {
+		return nil, false, err
+	}
+	return value, found, err
+}
+
+// GetStale предпочитает свежее значение из любого уровня устаревшему из L1
+func (t *TieredCache) GetStale(ctx context.Context, key string) (interface{}, bool, bool, error) {
+	l1Value, l1Stale, l1Found := t.l1.GetStale(key)
+	if l1Found && !l1Stale {
+		DefaultMetrics.Inc("cache.l1.hits")
+		return l1Value, false, true, nil
+	}
+	DefaultMetrics.Inc("cache.l1.misses")
+
+	// ttl == 0 - L2 не сообщает остаток времени жизни
+	var value interface{}
+	var ttl time.Duration
+	var stale, found bool
+	var err error
+	switch l2 := t.l2.(type) {
+	case entity.ExpiringCache:
+		value, ttl, found, err = l2.GetTTL(ctx, key)
+		stale = found && ttl <= 0
+	case entity.StaleCache:
+		value, stale, found, err = l2.GetStale(ctx, key)
+	default:
+		value, found, err = t.l2.Get(ctx, key)
+	}
+	switch {
+	case err != nil:
+		DefaultMetrics.Inc("cache.l2.errors")
+	case found && !stale:
+		DefaultMetrics.Inc("cache.l2.hits")
+		// В L1 значение живёт не дольше, чем в L2, иначе переживёт там и отрицательные записи
+		t.setL1(key, value, ttl)
+		return value, false, true, nil
+	case found:
+		DefaultMetrics.Inc("cache.l2.stale_hits")
+		return value, true, true, nil
+	default:
+		DefaultMetrics.Inc("cache.l2.misses")
+	}
+
+	if l1Found {
+		return l1Value, true, true, nil
+	}
+	return nil, false, false, err
+}
+
+func (t *TieredCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	t.setL1(key, value, ttl)
+	return t.l2.Set(ctx, key, value, ttl)
+}
+
+// setL1 кладёт значение в L1 на ttl, но не дольше TTL самого L1; ttl <= 0 - на TTL L1
+func (t *TieredCache) setL1(key string, value interface{}, ttl time.Duration) {
+	if ttl > 0 && ttl < t.l1.ttl {
+		t.l1.SetWithTTL(key, value, ttl)
+	} else {
+		t.l1.Set(key, value)
+	}
+}
+
+func (t *TieredCache) Delete(ctx context.Context, key string) error {
+	t.l1.Remove(key)
+	if err := t.l2.Delete(ctx, key); err != nil {
+		return err
+	}
+	t.publish(ctx, "key:"+key)
+	return nil
+}
+
+// Keys берёт ключи из L2, если он поддерживает администрирование, иначе из L1
+func (t *TieredCache) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if admin, ok := t.l2.(entity.CacheAdmin); ok {
+		return admin.Keys(ctx, prefix)
+	}
+	return t.l1.Keys(prefix), nil
+}
+
+func (t *TieredCache) Entry(ctx context.Context, key string) (entity.CacheEntryInfo, bool, error) {
+	if admin, ok := t.l2.(entity.CacheAdmin); ok {
+		info, found, err := admin.Entry(ctx, key)
+		if found || err != nil {
+			return info, found, err
+		}
+	}
+	info, found := t.l1.Peek(key)
+	return info, found, nil
+}
+
+func (t *TieredCache) DeleteKey(ctx context.Context, key string) (bool, error) {
+	removed := t.l1.Remove(key)
+	var err error
+	if admin, ok := t.l2.(entity.CacheAdmin); ok {
+		removed, err = admin.DeleteKey(ctx, key)
+	} else {
+		err = t.l2.Delete(ctx, key)
+	}
+	if err != nil {
+		return removed, err
+	}
+	t.publish(ctx, "key:"+key)
+	return removed, nil
+}
+
+func (t *TieredCache) DeletePrefix(ctx context.Context, prefix string) (int, error) {
+	removed := t.l1.RemovePrefix(prefix)
+	if admin, ok := t.l2.(entity.CacheAdmin); ok {
+		var err error
+		if removed, err = admin.DeletePrefix(ctx, prefix); err != nil {
+			return removed, err
+		}
+	}
+	t.publish(ctx, "prefix:"+prefix)
+	return removed, nil
+}
+
+func (t *TieredCache) Flush(ctx context.Context) error {
+	t.l1.Flush()
+	if admin, ok := t.l2.(entity.CacheAdmin); ok {
+		if err := admin.Flush(ctx); err != nil {
+			return err
+		}
+	}
+	t.publish(ctx, "flush")
+	return nil
+}
+
+// Stats возвращает статистику L2 (или L1, если L2 её не отдаёт); по уровням - TierStats
+func (t *TieredCache) Stats(ctx context.Context) (entity.CacheStats, error) {
+	if admin, ok := t.l2.(entity.CacheAdmin); ok {
+		return admin.Stats(ctx)
+	}
+	return t.l1.Stats(), nil
+}
+
+func (t *TieredCache) TierStats(ctx context.Context) ([]entity.TierStats, error) {
+	tiers := []entity.TierStats{{Name: "l1", Stats: t.l1.Stats()}}
+	admin, ok := t.l2.(entity.CacheAdmin)
+	if !ok {
+		return tiers, nil
+	}
+	stats, err := admin.Stats(ctx)
+	if err != nil {
+		return tiers, errors.Join(errors.New("l2 stats unavailable"), err)
+	}
+	return append(tiers, entity.TierStats{Name: "l2", Stats: stats}), nil
+}
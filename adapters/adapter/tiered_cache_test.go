@@ -0,0 +1,119 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTieredCachePromotesL2Hits(t *testing.T) {
+	srv := newRESPServer(t)
+	l2 := NewRedisCache(srv.addr())
+	defer l2.Close()
+	l1 := NewCache(time.Minute)
+	defer l1.Close()
+	tiered := NewTieredCache(l1, l2)
+	ctx := context.Background()
+
+	// Значение, записанное другой репликой, есть только в L2
+	if err := l2.Set(ctx, "search:key", "value", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, found, err := tiered.Get(ctx, "search:key")
+	if err != nil || !found || value != "value" {
+		t.Fatalf("expected L2 hit, got %v found=%v err=%v", value, found, err)
+	}
+	if _, ok := l1.Get("search:key"); !ok {
+		t.Error("expected L2 hit to be promoted to L1")
+	}
+
+	if err := tiered.Set(ctx, "search:other", "other", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := l2.Get(ctx, "search:other"); !found {
+		t.Error("expected write-through to L2")
+	}
+}
+
+func TestTieredCachePromotionKeepsL2Expiry(t *testing.T) {
+	srv := newRESPServer(t)
+	l2 := NewRedisCache(srv.addr())
+	defer l2.Close()
+	l1 := NewCache(time.Minute)
+	defer l1.Close()
+	tiered := NewTieredCache(l1, l2)
+	ctx := context.Background()
+
+	// Короткоживущая запись (например, отрицательная) не должна жить в L1 по TTL L1
+	if err := l2.Set(ctx, "search:negative", "empty", 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := tiered.Get(ctx, "search:negative"); !found {
+		t.Fatal("expected L2 hit")
+	}
+	_, ttl, found := l1.GetTTL("search:negative")
+	if !found || ttl > 50*time.Millisecond {
+		t.Fatalf("expected promoted entry to keep the L2 expiry, got ttl %v found=%v", ttl, found)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, found, _ := tiered.Get(ctx, "search:negative"); found {
+		t.Error("expected the entry to expire together with L2")
+	}
+}
+
+func TestTieredCacheBroadcastsInvalidation(t *testing.T) {
+	srv := newRESPServer(t)
+	ctx := context.Background()
+	// Две реплики с общим L2 и собственными L1
+	newReplica := func() (*TieredCache, *Cache) {
+		l2 := NewRedisCache(srv.addr())
+		t.Cleanup(func() { l2.Close() })
+		l1 := NewCache(time.Minute)
+		t.Cleanup(l1.Close)
+		tiered := NewTieredCache(l1, l2)
+		t.Cleanup(tiered.Close)
+		return tiered, l1
+	}
+	first, _ := newReplica()
+	second, secondL1 := newReplica()
+	srv.waitSubscribers(t, DefaultRedisKeyPrefix+invalidationChannel, 2)
+
+	for _, key := range []string{"search:a", "search:b", "geocode:c"} {
+		if err := first.Set(ctx, key, "value", 0); err != nil {
+			t.Fatal(err)
+		}
+		// Вторая реплика поднимает значение в свой L1
+		if _, found, _ := second.Get(ctx, key); !found {
+			t.Fatalf("expected %s in the second replica", key)
+		}
+	}
+
+	waitGone := func(key string) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for {
+			if _, ok := secondL1.Get(key); !ok {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %s to be invalidated in the second replica", key)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if _, err := first.DeleteKey(ctx, "search:a"); err != nil {
+		t.Fatal(err)
+	}
+	waitGone("search:a")
+	if _, err := first.DeletePrefix(ctx, "search:"); err != nil {
+		t.Fatal(err)
+	}
+	waitGone("search:b")
+	if _, ok := secondL1.Get("geocode:c"); !ok {
+		t.Error("expected keys outside the prefix to stay in L1")
+	}
+	if err := first.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	waitGone("geocode:c")
+}
@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+var (
+	namespacesMu sync.Mutex
+	// namespaces запоминает тип значений каждого пространства ключей
+	namespaces = make(map[string]reflect.Type)
+)
+
+// TypedCache - типизированный вид entity.Cache с собственным пространством ключей.
+// Ключи хранятся как "<namespace>:<key>", и одно пространство не может быть занято
+// значениями разных типов, поэтому записи разных use case'ов не пересекаются.
+type TypedCache[K comparable, V any] struct {
+	backend   entity.Cache
+	namespace string
+}
+
+// NewTypedCache создаёт типизированный кэш. Паникует, если namespace уже используется
+// для другого типа значений - это ошибка программиста, а не данных.
+func NewTypedCache[K comparable, V any](backend entity.Cache, namespace string) *TypedCache[K, V] {
+	typ := reflect.TypeOf((*V)(nil)).Elem()
+
+	namespacesMu.Lock()
+	defer namespacesMu.Unlock()
+	if prev, ok := namespaces[namespace]; ok {
+		if prev != typ {
+			panic(fmt.Sprintf("cache namespace %q is already used for %s, not %s", namespace, prev, typ))
+		}
+	} else {
+		namespaces[namespace] = typ
+		if typ.Kind() != reflect.Interface {
+			var zero V
+			RegisterCacheType(zero)
+		}
+	}
+	return &TypedCache[K, V]{backend: backend, namespace: namespace}
+}
+
+func (c *TypedCache[K, V]) Namespace() string {
+	return c.namespace
+}
+
+// Key возвращает ключ в бэкенде для k
+func (c *TypedCache[K, V]) Key(k K) string {
+	return c.namespace + ":" + fmt.Sprint(k)
+}
+
+func (c *TypedCache[K, V]) Get(ctx context.Context, k K) (V, bool, error) {
+	value, stale, found, err := c.GetStale(ctx, k)
+	if err != nil || stale {
+		var zero V
+		return zero, false, err
+	}
+	return value, found, nil
+}
+
+// GetStale отдаёт устаревшие значения, если бэкенд это поддерживает (entity.StaleCache)
+func (c *TypedCache[K, V]) GetStale(ctx context.Context, k K) (value V, stale bool, found bool, err error) {
+	var raw interface{}
+	if staleCache, ok := c.backend.(entity.StaleCache); ok {
+		raw, stale, found, err = staleCache.GetStale(ctx, c.Key(k))
+	} else {
+		raw, found, err = c.backend.Get(ctx, c.Key(k))
+	}
+	if err != nil || !found {
+		return value, false, false, err
+	}
+	value, ok := raw.(V)
+	if !ok {
+		// Чужое значение под нашим ключом считаем промахом
+		DefaultMetrics.Inc("cache.type_mismatch")
+		return value, false, false, nil
+	}
+	return value, stale, true, nil
+}
+
+func (c *TypedCache[K, V]) Set(ctx context.Context, k K, value V, ttl time.Duration) error {
+	return c.backend.Set(ctx, c.Key(k), value, ttl)
+}
+
+func (c *TypedCache[K, V]) Delete(ctx context.Context, k K) error {
+	return c.backend.Delete(ctx, c.Key(k))
+}
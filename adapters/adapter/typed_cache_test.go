@@ -0,0 +1,44 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestTypedCacheNamespaces(t *testing.T) {
+	cache := NewCache(time.Minute)
+	defer cache.Close()
+	backend := NewMemoryCache(cache)
+	ctx := context.Background()
+
+	results := NewTypedCache[string, entity.ResponseAddresses](backend, "test-geocode")
+	probes := NewTypedCache[string, string](backend, "test-health")
+
+	if err := probes.Set(ctx, "key", "probe", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := results.Get(ctx, "key"); found {
+		t.Error("expected namespaces not to collide")
+	}
+
+	// Значение чужого типа под ключом пространства - промах, а не паника
+	cache.Set(results.Key("broken"), "not an address")
+	if _, found, err := results.Get(ctx, "broken"); found || err != nil {
+		t.Errorf("expected miss on type mismatch, got found=%v err=%v", found, err)
+	}
+}
+
+func TestTypedCacheNamespaceTypeConflict(t *testing.T) {
+	backend := NewMemoryCache(NewCache(time.Minute))
+	NewTypedCache[string, string](backend, "test-conflict")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic when reusing namespace for another type")
+		}
+	}()
+	NewTypedCache[string, int](backend, "test-conflict")
+}
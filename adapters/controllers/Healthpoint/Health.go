@@ -7,15 +7,21 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
 	"time"
 
 	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
-	"studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/repository"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
 )
 
-func Healthpoint(cache *adapter.Cache, geoService *repository.GeoRepo) {
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+func Healthpoint(cache entity.Cache, geoService entity.GeoProvider) {
+	http.HandleFunc("/health", Handler(cache, geoService))
+}
+
+// Handler проверяет провайдер и кэш и перечисляет состояния автоматов защиты провайдеров
+func Handler(cache entity.Cache, geoService entity.GeoProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
 		// 1. Проверка geoService
 		if geoService == nil {
 			log.Println("geoService is nil")
@@ -32,22 +38,46 @@ func Healthpoint(cache *adapter.Cache, geoService *repository.GeoRepo) {
 			return
 		}
 
-		//Попытка записи и чтения из кэша
+		//Попытка записи и чтения из кэша в собственном пространстве ключей health
+		probes := adapter.NewTypedCache[string, string](cache, "health")
 		testKey := "healthcheck_test_key"
 		testValue := "healthcheck_test_value"
-		cache.Set(testKey, testValue)
-		_, found := cache.Get(testKey)
-		if !found {
+		if err := probes.Set(r.Context(), testKey, testValue, 0); err != nil {
+			log.Println("Cache test failed:", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, "Cache test failed")
+			return
+		}
+		_, found, err := probes.Get(r.Context(), testKey)
+		if err != nil || !found {
 			log.Println("Cache test failed")
 			w.WriteHeader(http.StatusServiceUnavailable)
 			fmt.Fprintln(w, "Cache test failed")
 			return
 		}
 
+		// Разомкнутый автомат не роняет сервис целиком, но отмечается как DEGRADED
+		status := "OK"
+		var states map[string]string
+		if reporter, ok := geoService.(entity.BreakerReporter); ok {
+			states = reporter.BreakerStates()
+		}
+		names := make([]string, 0, len(states))
+		for name, state := range states {
+			names = append(names, name)
+			if state != adapter.BreakerClosed.String() {
+				status = "DEGRADED"
+			}
+		}
+		sort.Strings(names)
+
 		// Все проверки прошли успешно
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprintln(w, "OK")
-	})
+		fmt.Fprintln(w, status)
+		for _, name := range names {
+			fmt.Fprintf(w, "breaker %s: %s\n", name, states[name])
+		}
+	}
 }
 
 // --- Конец Health Check Endpoint ---
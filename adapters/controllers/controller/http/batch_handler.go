@@ -0,0 +1,122 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+// batchWriteMargin - запас дедлайна записи пакета на кэш и сериализацию ответа
+const batchWriteMargin = 10 * time.Second
+
+// batchRequest - тело пакетного запроса. Элементы в том же формате, что у одиночных
+// запросов, и разбираются по одному, чтобы ошибка в одном не отклоняла весь пакет.
+type batchRequest struct {
+	Items []json.RawMessage `json:"items"`
+}
+
+type batchResponse struct {
+	Results []batchItem `json:"results"`
+}
+
+// batchItem - результат элемента пакета: адреса или ошибка
+type batchItem struct {
+	Addresses []*entity.Address `json:"addresses,omitempty"`
+	Provider  string            `json:"provider,omitempty"`
+	Error     *batchError       `json:"error,omitempty"`
+}
+
+// batchError - ошибка элемента с тем статусом, который получил бы одиночный запрос
+type batchError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func geocodeBatchHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleBatch(resp, w, r, "geocode", func(ctx context.Context, reqs []entity.GeocodeRequest) ([]usecase.BatchResult, error) {
+			return usecase.HandleGeocodeBatch(ctx, reqs, geoService, cache)
+		})
+	}
+}
+
+func searchBatchHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleBatch(resp, w, r, "search", func(ctx context.Context, reqs []entity.RequestAddressSearch) ([]usecase.BatchResult, error) {
+			return usecase.HandleSearchBatch(ctx, reqs, geoService, cache)
+		})
+	}
+}
+
+// handleBatch разбирает элементы пакета, выполняет корректные через run и отвечает
+// результатами в порядке элементов запроса. Поддерживает ?detail=basic|full.
+// WriteTimeout сервера рассчитан на одиночный запрос, поэтому дедлайн записи ответа
+// продлевается на худшее время пакета по usecase.BatchTimeout.
+func handleBatch[T any](resp entity.Responder, w http.ResponseWriter, r *http.Request, kind string, run func(ctx context.Context, reqs []T) ([]usecase.BatchResult, error)) {
+	detail, err := entity.ParseDetailLevel(r.URL.Query().Get("detail"))
+	if err != nil {
+		resp.ErrorBadRequest(w, err)
+		return
+	}
+	var body batchRequest
+	if err := decodeRequest(r, &body); err != nil {
+		resp.ErrorBadRequest(w, err)
+		return
+	}
+	if err := usecase.ValidateBatchSize(len(body.Items)); err != nil {
+		resp.ErrorBadRequest(w, err)
+		return
+	}
+
+	results := make([]usecase.BatchResult, len(body.Items))
+	reqs := make([]T, 0, len(body.Items))
+	index := make([]int, 0, len(body.Items))
+	for i, raw := range body.Items {
+		var req T
+		if err := json.Unmarshal(raw, &req); err != nil {
+			results[i].Err = fieldError(err)
+			continue
+		}
+		reqs = append(reqs, req)
+		index = append(index, i)
+	}
+	if len(reqs) > 0 {
+		if timeout := usecase.BatchTimeout(kind, len(reqs)); timeout > 0 {
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + batchWriteMargin))
+		}
+		batch, err := run(r.Context(), reqs)
+		if err != nil {
+			writeGeoError(resp, w, err)
+			return
+		}
+		for j, result := range batch {
+			results[index[j]] = result
+		}
+	}
+
+	out := batchResponse{Results: make([]batchItem, len(results))}
+	for i, result := range results {
+		if result.Err != nil {
+			out.Results[i].Error = newBatchError(result.Err)
+			continue
+		}
+		geo := result.Geo.WithDetail(detail)
+		out.Results[i] = batchItem{Addresses: geo.Addresses, Provider: geo.Provider}
+	}
+	resp.OutputJSON(w, out)
+}
+
+func newBatchError(err error) *batchError {
+	e := &batchError{Status: geoErrorStatus(err), Message: err.Error()}
+	var fieldErr *entity.FieldError
+	if errors.As(err, &fieldErr) {
+		e.Field = fieldErr.Field
+	}
+	return e
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// cacheKeysHandler возвращает ключи кэша, например ?prefix=geocode:
+func cacheKeysHandler(resp entity.Responder, admin entity.CacheAdmin) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := admin.Keys(r.Context(), r.URL.Query().Get("prefix"))
+		if err != nil {
+			resp.ErrorInternal(w, err)
+			return
+		}
+		resp.OutputJSON(w, keys)
+	}
+}
+
+func cacheEntryHandler(resp entity.Responder, admin entity.CacheAdmin) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			resp.ErrorBadRequest(w, errors.New("key is required"))
+			return
+		}
+		info, found, err := admin.Entry(r.Context(), key)
+		if err != nil {
+			resp.ErrorInternal(w, err)
+			return
+		}
+		if !found {
+			resp.ErrorNotFound(w, fmt.Errorf("key %q not found", key))
+			return
+		}
+		resp.OutputJSON(w, info)
+	}
+}
+
+// cacheDeleteHandler удаляет один ключ (?key=) или все ключи с префиксом (?prefix=).
+// В tiered-кэше удаление рассылается остальным репликам, см. adapter.TieredCache.
+func cacheDeleteHandler(resp entity.Responder, admin entity.CacheAdmin) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, prefix := r.URL.Query().Get("key"), r.URL.Query().Get("prefix")
+		var deleted int
+		var err error
+		switch {
+		case key != "":
+			var found bool
+			if found, err = admin.DeleteKey(r.Context(), key); found {
+				deleted = 1
+			}
+		case prefix != "":
+			deleted, err = admin.DeletePrefix(r.Context(), prefix)
+		default:
+			resp.ErrorBadRequest(w, errors.New("key or prefix is required"))
+			return
+		}
+		if err != nil {
+			resp.ErrorInternal(w, err)
+			return
+		}
+		resp.OutputJSON(w, entity.Response{Success: true, Data: map[string]int{"deleted": deleted}})
+	}
+}
+
+func cacheFlushHandler(resp entity.Responder, admin entity.CacheAdmin) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := admin.Flush(r.Context()); err != nil {
+			resp.ErrorInternal(w, err)
+			return
+		}
+		resp.OutputJSON(w, entity.Response{Success: true})
+	}
+}
+
+func cacheStatsHandler(resp entity.Responder, admin entity.CacheAdmin) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := admin.Stats(r.Context())
+		if err != nil {
+			resp.ErrorInternal(w, err)
+			return
+		}
+		resp.OutputJSON(w, stats)
+	}
+}
+
+// cacheTiersHandler показывает попадания по уровням многоуровневого кэша
+func cacheTiersHandler(resp entity.Responder, tiered entity.TieredCacheStats) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tiers, err := tiered.TierStats(r.Context())
+		if err != nil {
+			resp.ErrorInternal(w, err)
+			return
+		}
+		resp.OutputJSON(w, tiers)
+	}
+}
@@ -1,18 +1,19 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
-	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
 	"studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/repository"
 	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
 	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
 )
 
 type GeoService interface {
-	GetGeoCoordinatesAddress(query string) (entity.ResponseAddresses, error)
-	GetGeoCoordinatesGeocode(lat float64, lng float64) (entity.ResponseAddresses, error)
+	GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error)
+	GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error)
 }
 
 type GeoSvc struct {
@@ -23,44 +24,123 @@ type RequestAddressSearch struct {
 	Query string `json:"query"`
 }
 
-func (s *GeoSvc) GetGeoCoordinatesGeocode(lat float64, lng float64) (entity.ResponseAddresses, error) {
-	return s.repo.GetGeoCoordinatesGeocode(lat, lng)
+func (s *GeoSvc) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	return s.repo.GetGeoCoordinatesGeocode(ctx, lat, lng)
 }
 
-func (s *GeoSvc) GetGeoCoordinatesAddress(query string) (entity.ResponseAddresses, error) {
-	return s.repo.GetGeoCoordinatesAddress(query)
+func (s *GeoSvc) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	return s.repo.GetGeoCoordinatesAddress(ctx, query)
 }
 
-func geocodeHandler(resp entity.Responder, geoService entity.GeoProvider, cache *adapter.Cache) http.HandlerFunc {
+// Подробность ответа задаётся параметром ?detail=basic|full. В кэше хранится полный ответ,
+// поэтому от уровня подробности ключ кэша не зависит.
+func geocodeHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		detail, err := entity.ParseDetailLevel(r.URL.Query().Get("detail"))
+		if err != nil {
+			resp.ErrorBadRequest(w, err)
+			return
+		}
 		var req entity.GeocodeRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeRequest(r, &req); err != nil {
 			resp.ErrorBadRequest(w, err)
 			return
 		}
 
-		geo, err := usecase.HandleGeocodeRequest(req, geoService, cache)
+		geo, err := usecase.HandleGeocodeRequest(r.Context(), req, geoService, cache)
 		if err != nil {
-			resp.ErrorInternal(w, err)
+			writeGeoError(resp, w, err)
 			return
 		}
-		resp.OutputJSON(w, geo)
+		setProviderHeader(w, geo)
+		resp.OutputJSON(w, geo.WithDetail(detail))
 	}
 }
 
-func searchHandler(resp entity.Responder, geoService entity.GeoProvider, cache *adapter.Cache) http.HandlerFunc {
+func searchHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		detail, err := entity.ParseDetailLevel(r.URL.Query().Get("detail"))
+		if err != nil {
+			resp.ErrorBadRequest(w, err)
+			return
+		}
 		var req entity.RequestAddressSearch
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeRequest(r, &req); err != nil {
 			resp.ErrorBadRequest(w, err)
 			return
 		}
 
-		geo, err := usecase.HandleGeocodeAddressReq(req, geoService, cache)
+		geo, err := usecase.HandleGeocodeAddressReq(r.Context(), req, geoService, cache)
 		if err != nil {
-			resp.ErrorInternal(w, err)
+			writeGeoError(resp, w, err)
 			return
 		}
-		resp.OutputJSON(w, geo)
+		setProviderHeader(w, geo)
+		resp.OutputJSON(w, geo.WithDetail(detail))
+	}
+}
+
+// decodeRequest читает JSON-тело запроса. Значение не того типа превращается
+// в *entity.FieldError, чтобы клиент видел, какое поле неверно.
+func decodeRequest(r *http.Request, dst interface{}) error {
+	return fieldError(json.NewDecoder(r.Body).Decode(dst))
+}
+
+func fieldError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &entity.FieldError{Field: typeErr.Field, Message: "invalid value " + typeErr.Value}
+	}
+	return err
+}
+
+// setProviderHeader сообщает клиенту, какой провайдер дал ответ
+func setProviderHeader(w http.ResponseWriter, geo entity.ResponseAddresses) {
+	if geo.Provider != "" {
+		w.Header().Set("X-Geo-Provider", geo.Provider)
+	}
+}
+
+// geoErrorStatus - HTTP-статус ошибки геозапроса: 400 на некорректное поле запроса, 503 - если
+// провайдер отключён автоматом защиты или упёрся в лимиты, 504 - если провайдер не уложился
+// в дедлайн. Неуспешный ответ провайдера даёт 429, если провайдер ограничил частоту, 503 - если
+// исчерпана квота, иначе 502. Остальное - 500.
+func geoErrorStatus(err error) int {
+	var fieldErr *entity.FieldError
+	var upstream *entity.UpstreamError
+	switch {
+	case errors.As(err, &fieldErr):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, entity.ErrCircuitOpen) || errors.Is(err, entity.ErrRateLimited) ||
+		errors.Is(err, entity.ErrQuotaExhausted):
+		return http.StatusServiceUnavailable
+	case errors.As(err, &upstream):
+		switch {
+		case upstream.StatusCode == http.StatusTooManyRequests:
+			return http.StatusTooManyRequests
+		case upstream.Quota():
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeGeoError(resp entity.Responder, w http.ResponseWriter, err error) {
+	switch geoErrorStatus(err) {
+	case http.StatusBadRequest:
+		resp.ErrorBadRequest(w, err)
+	case http.StatusGatewayTimeout:
+		resp.ErrorGatewayTimeout(w, err)
+	case http.StatusServiceUnavailable:
+		resp.ErrorServiceUnavailable(w, err)
+	case http.StatusBadGateway:
+		resp.ErrorBadGateway(w, err)
+	case http.StatusTooManyRequests:
+		resp.ErrorTooManyRequests(w, err)
+	default:
+		resp.ErrorInternal(w, err)
 	}
 }
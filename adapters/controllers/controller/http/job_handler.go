@@ -0,0 +1,171 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+// maxJobUpload - наибольший размер входного файла задания
+const maxJobUpload = 64 << 20
+
+// jobOwner - владелец заданий из токена запроса: случайный ID учётной записи, а не имя,
+// которое после перезапуска может зарегистрировать кто угодно
+func jobOwner(r *http.Request) string {
+	_, claims, _ := jwtauth.FromContext(r.Context())
+	owner, _ := claims[entity.UserIDClaim].(string)
+	return owner
+}
+
+// writeJobError отвечает 404 на чужое или несуществующее задание, 409 - на результаты
+// незавершённого задания, 400 - на некорректный запрос
+func writeJobError(resp entity.Responder, w http.ResponseWriter, err error) {
+	var fieldErr *entity.FieldError
+	switch {
+	case errors.Is(err, entity.ErrJobNotFound):
+		resp.ErrorNotFound(w, err)
+	case errors.Is(err, entity.ErrJobNotFinished):
+		resp.ErrorConflict(w, err)
+	case errors.As(err, &fieldErr):
+		resp.ErrorBadRequest(w, err)
+	default:
+		resp.ErrorInternal(w, err)
+	}
+}
+
+// jobSubmitHandler принимает входной файл задания телом запроса или полем file в multipart/form-data.
+// Параметры: ?kind=search|geocode (по умолчанию search), ?format=csv|ndjson - если формат
+// не понятен из Content-Type или расширения файла.
+func jobSubmitHandler(resp entity.Responder, jobs *usecase.JobManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kind := entity.JobKind(r.URL.Query().Get("kind"))
+		if kind == "" {
+			kind = entity.JobSearch
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxJobUpload)
+
+		var body io.Reader = r.Body
+		format := r.URL.Query().Get("format")
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "multipart/form-data" {
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				resp.ErrorBadRequest(w, &entity.FieldError{Field: "file", Message: err.Error()})
+				return
+			}
+			defer file.Close()
+			body = file
+			if format == "" {
+				format = jobFormat(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+			}
+		} else if format == "" {
+			format = jobFormat(mediaType)
+		}
+
+		items, err := usecase.ParseJobInput(body, format, kind)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				err = &entity.FieldError{Field: "file", Message: err.Error()}
+			}
+			writeJobError(resp, w, err)
+			return
+		}
+		job, err := jobs.Submit(jobOwner(r), kind, items)
+		if err != nil {
+			writeJobError(resp, w, err)
+			return
+		}
+		w.Header().Set("Location", "/api/jobs/"+job.ID)
+		w.Header().Set("Content-Type", "application/json;charset=utf-8")
+		w.WriteHeader(http.StatusAccepted)
+		resp.OutputJSON(w, job)
+	}
+}
+
+// jobFormat угадывает формат по Content-Type или расширению файла
+func jobFormat(s string) string {
+	switch strings.ToLower(s) {
+	case "csv", "text/csv", "application/csv":
+		return usecase.JobFormatCSV
+	case "ndjson", "jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl", "application/json":
+		return usecase.JobFormatNDJSON
+	}
+	return s
+}
+
+func jobListHandler(resp entity.Responder, jobs *usecase.JobManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := jobs.List(jobOwner(r))
+		if list == nil {
+			list = []entity.Job{}
+		}
+		resp.OutputJSON(w, list)
+	}
+}
+
+func jobStatusHandler(resp entity.Responder, jobs *usecase.JobManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := jobs.Get(jobOwner(r), chi.URLParam(r, "id"))
+		if err != nil {
+			writeJobError(resp, w, err)
+			return
+		}
+		resp.OutputJSON(w, job)
+	}
+}
+
+func jobCancelHandler(resp entity.Responder, jobs *usecase.JobManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := jobs.Cancel(jobOwner(r), chi.URLParam(r, "id"))
+		if err != nil {
+			writeJobError(resp, w, err)
+			return
+		}
+		resp.OutputJSON(w, job)
+	}
+}
+
+// jobResultsHandler отдаёт результаты завершённого задания: ?format=csv|ndjson, по умолчанию ndjson.
+// Поддерживает ?detail=basic|full. Результаты пишутся по мере чтения из хранилища.
+func jobResultsHandler(resp entity.Responder, jobs *usecase.JobManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		detail, err := entity.ParseDetailLevel(r.URL.Query().Get("detail"))
+		if err != nil {
+			resp.ErrorBadRequest(w, err)
+			return
+		}
+		format := jobFormat(r.URL.Query().Get("format"))
+		if format == "" {
+			format = usecase.JobFormatNDJSON
+		}
+		if format != usecase.JobFormatCSV && format != usecase.JobFormatNDJSON {
+			resp.ErrorBadRequest(w, &entity.FieldError{Field: "format", Message: "expected csv or ndjson"})
+			return
+		}
+		job, each, err := jobs.Results(jobOwner(r), chi.URLParam(r, "id"))
+		if err != nil {
+			writeJobError(resp, w, err)
+			return
+		}
+
+		contentType := "application/x-ndjson"
+		if format == usecase.JobFormatCSV {
+			contentType = "text/csv;charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="job-`+job.ID+`.`+format+`"`)
+		// Заголовок уже отправлен, поэтому ошибку записи можно только оборвать соединением
+		if err := usecase.WriteJobResults(w, format, job, detail, each); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
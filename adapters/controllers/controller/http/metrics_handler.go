@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+func metricsHandler(resp entity.Responder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp.OutputJSON(w, adapter.DefaultMetrics.Snapshot())
+	}
+}
+
+// geoKeyStatsHandler показывает hit rate кэша обратного геокодирования по стратегиям ключей
+func geoKeyStatsHandler(resp entity.Responder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp.OutputJSON(w, usecase.GeoKeyStats())
+	}
+}
+
+// quotaHandler показывает лимиты частоты и остаток суточной квоты провайдеров
+func quotaHandler(resp entity.Responder, quotas entity.QuotaReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp.OutputJSON(w, quotas.Quotas())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/go-chi/jwtauth"
 	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
 )
 
@@ -19,12 +20,30 @@ func TokenAuthMiddleware(resp entity.Responder) func(http.Handler) http.Handler
 
 			token = strings.TrimPrefix(token, "Bearer ")
 
-			_, err := entity.TokenAuth.Decode(token)
+			t, err := entity.TokenAuth.Decode(token)
 			if err != nil {
 				resp.ErrorUnauthorized(w, err)
 				return
 			}
 
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(r.Context(), t, nil)))
+		})
+	}
+}
+
+// AdminOnly пропускает только токены с ролью admin. Ставится после TokenAuthMiddleware.
+func AdminOnly(resp entity.Responder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				resp.ErrorUnauthorized(w, err)
+				return
+			}
+			if role, _ := claims[entity.RoleClaim].(string); role != entity.RoleAdmin {
+				resp.ErrorForbidden(w, errors.New("admin role required"))
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
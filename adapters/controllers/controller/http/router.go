@@ -8,18 +8,39 @@ import (
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
-	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	healthpoint "studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/Healthpoint"
 	"studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/repository"
 	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
 )
 
-func Router(resp entity.Responder, geoService entity.GeoProvider, cache *adapter.Cache) http.Handler {
+// RouterOption подключает к роутеру необязательные подсистемы
+type RouterOption func(*routerConfig)
+
+type routerConfig struct {
+	jobs *usecase.JobManager
+}
+
+// WithJobs включает асинхронные задания /api/jobs
+func WithJobs(jobs *usecase.JobManager) RouterOption {
+	return func(c *routerConfig) {
+		c.jobs = jobs
+	}
+}
+
+func Router(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache, opts ...RouterOption) http.Handler {
+	var cfg routerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	// Public routes (без авторизации)
 	r.Get("/swagger/*", httpSwagger.WrapHandler) // Swagger остаётся публичным
+	r.Get("/health", healthpoint.Handler(cache, geoService))
 
 	// API routes
 	r.Post("/api/register", repository.Register)
@@ -32,6 +53,40 @@ func Router(resp entity.Responder, geoService entity.GeoProvider, cache *adapter
 		// API endpoints
 		r.Post("/api/address/geocode", geocodeHandler(resp, geoService, cache))
 		r.Post("/api/address/search", searchHandler(resp, geoService, cache))
+		r.Post("/api/address/geocode/batch", geocodeBatchHandler(resp, geoService, cache))
+		r.Post("/api/address/search/batch", searchBatchHandler(resp, geoService, cache))
+		r.Post("/api/address/geocode/stream", geocodeStreamHandler(resp, geoService, cache))
+		r.Post("/api/address/search/stream", searchStreamHandler(resp, geoService, cache))
+		if cfg.jobs != nil {
+			r.Post("/api/jobs", jobSubmitHandler(resp, cfg.jobs))
+			r.Get("/api/jobs", jobListHandler(resp, cfg.jobs))
+			r.Get("/api/jobs/{id}", jobStatusHandler(resp, cfg.jobs))
+			r.Get("/api/jobs/{id}/results", jobResultsHandler(resp, cfg.jobs))
+			r.Delete("/api/jobs/{id}", jobCancelHandler(resp, cfg.jobs))
+		}
+		r.Get("/api/metrics", metricsHandler(resp))
+		r.Get("/api/metrics/geokey", geoKeyStatsHandler(resp))
+
+		// Администрирование (только для роли admin)
+		r.Group(func(r chi.Router) {
+			r.Use(AdminOnly(resp))
+			if quotas, ok := geoService.(entity.QuotaReporter); ok {
+				r.Get("/api/admin/quota", quotaHandler(resp, quotas))
+			}
+			if admin, ok := cache.(entity.CacheAdmin); ok {
+				r.Get("/api/admin/cache/keys", cacheKeysHandler(resp, admin))
+				r.Delete("/api/admin/cache/keys", cacheDeleteHandler(resp, admin))
+				r.Get("/api/admin/cache/entry", cacheEntryHandler(resp, admin))
+				r.Get("/api/admin/cache/stats", cacheStatsHandler(resp, admin))
+				r.Delete("/api/admin/cache", cacheFlushHandler(resp, admin))
+				warm := &warmState{}
+				r.Post("/api/admin/cache/warm", warmStartHandler(resp, geoService, cache, warm))
+				r.Get("/api/admin/cache/warm", warmStatusHandler(resp, warm))
+				if tiered, ok := cache.(entity.TieredCacheStats); ok {
+					r.Get("/api/admin/cache/tiers", cacheTiersHandler(resp, tiered))
+				}
+			}
+		})
 
 		// Pprof endpoints
 		r.Handle("/mycustompath/pprof/*", http.HandlerFunc(NetPprof.Index))
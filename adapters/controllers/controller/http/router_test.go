@@ -0,0 +1,623 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/repository"
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/dadatafake"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+// newTestRouter поднимает весь роутер поверх поддельного DaData и возвращает токен пользователя
+func newTestRouter(t *testing.T) (*httptest.Server, *dadatafake.Server, string) {
+	t.Helper()
+	// Выданные токены пишутся в файл; тесты не должны оставлять его в репозитории
+	usecase.SetTokenFile(filepath.Join(t.TempDir(), "tokens.json"))
+	fake := dadatafake.New(dadatafake.WithAPIKey("test-key"))
+	t.Cleanup(fake.Close)
+
+	geo, err := repository.NewGeoService("test-key", "test-secret",
+		repository.WithBaseURL(fake.BaseURL()),
+		repository.WithRetryPolicy(repository.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := adapter.NewCache(time.Minute)
+	t.Cleanup(cache.Close)
+	memCache := adapter.NewMemoryCache(cache)
+
+	store, err := adapter.NewFileJobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := usecase.NewJobManager(store, geo, memCache, usecase.DefaultJobOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(Router(repository.NewResponder(zap.NewNop()), geo, memCache, WithJobs(jobs)))
+	t.Cleanup(srv.Close)
+	t.Cleanup(jobs.Close)
+
+	return srv, fake, login(t, srv, t.Name())
+}
+
+// login регистрирует пользователя и возвращает его токен
+func login(t *testing.T, srv *httptest.Server, username string) string {
+	t.Helper()
+	user := entity.User{Username: username, Password: "password"}
+	if resp := doJSON(t, srv, "/api/register", "", user); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("register: unexpected status %d", resp.StatusCode)
+	}
+	return signIn(t, srv, user)
+}
+
+// signIn входит под уже существующим пользователем и возвращает его токен
+func signIn(t *testing.T, srv *httptest.Server, user entity.User) string {
+	t.Helper()
+	resp := doJSON(t, srv, "/api/login", "", user)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login: unexpected status %d", resp.StatusCode)
+	}
+	var token entity.TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		t.Fatal(err)
+	}
+	return token.Token
+}
+
+func doJSON(t *testing.T, srv *httptest.Server, path, token string, body interface{}) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doRequest(t, srv, "POST", path, token, "application/json", bytes.NewReader(data))
+}
+
+func doRequest(t *testing.T, srv *httptest.Server, method, path, token, contentType string, body io.Reader) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decodeAddresses(t *testing.T, resp *http.Response) []*entity.Address {
+	t.Helper()
+	var geo entity.ResponseAddresses
+	if err := json.NewDecoder(resp.Body).Decode(&geo); err != nil {
+		t.Fatal(err)
+	}
+	return geo.Addresses
+}
+
+func TestRouterSearch(t *testing.T) {
+	srv, fake, token := newTestRouter(t)
+
+	if resp := doJSON(t, srv, "/api/address/search", "", entity.RequestAddressSearch{Query: "москва"}); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", resp.StatusCode)
+	}
+
+	resp := doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: "москва тверская"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Координаты в ответе остаются строками, как и до перехода на entity.Coordinate
+	if !bytes.Contains(body, []byte(`"geo_lat":"55.7573"`)) {
+		t.Errorf("expected string coordinates in %s", body)
+	}
+	var geo entity.ResponseAddresses
+	if err := json.Unmarshal(body, &geo); err != nil {
+		t.Fatal(err)
+	}
+	addresses := geo.Addresses
+	if len(addresses) != 2 || addresses[0].Street != "Тверская" || addresses[0].Lat != 55.7573 {
+		t.Errorf("unexpected addresses %+v", addresses)
+	}
+
+	// Повторный запрос отдаётся из кэша и до DaData не доходит
+	requests := len(fake.Requests())
+	doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: "москва тверская"})
+	if got := len(fake.Requests()); got != requests {
+		t.Errorf("expected cached response, got %d upstream requests", got-requests)
+	}
+}
+
+func TestRouterGeocode(t *testing.T) {
+	srv, _, token := newTestRouter(t)
+
+	resp := doJSON(t, srv, "/api/address/geocode", token, entity.GeocodeRequest{Lat: 55.7538, Lng: 37.6207})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	addresses := decodeAddresses(t, resp)
+	if len(addresses) != 1 || addresses[0].Street != "Красная" || addresses[0].House != "1" {
+		t.Errorf("unexpected addresses %+v", addresses)
+	}
+}
+
+func TestRouterInvalidCoordinates(t *testing.T) {
+	srv, fake, token := newTestRouter(t)
+
+	for _, tc := range []struct {
+		body  string
+		field string
+	}{
+		{`{"lat": 999, "lng": 37.6}`, "lat"},
+		{`{"lat": 55.7, "lng": -180.5}`, "lng"},
+		{`{"lat": "NaN", "lng": 37.6}`, "lat"},
+		{`{"lat": 55.7, "lng": "abc"}`, "lng"},
+		// Без координаты запрос не превращается в точку 0,0
+		{`{"lng": 37.6}`, "lat"},
+		{`{"lat": 55.7}`, "lng"},
+		{`{"lat": null, "lng": 37.6}`, "lat"},
+	} {
+		req, _ := http.NewRequest("POST", srv.URL+"/api/address/geocode", bytes.NewReader([]byte(tc.body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Data entity.FieldError `json:"data"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest || body.Data.Field != tc.field {
+			t.Errorf("%s: expected 400 for field %s, got %d %+v", tc.body, tc.field, resp.StatusCode, body.Data)
+		}
+	}
+	if got := len(fake.Requests()); got != 0 {
+		t.Errorf("invalid coordinates must not reach DaData, got %d requests", got)
+	}
+
+	// Координаты в строковом виде, как их отдают провайдеры, тоже принимаются
+	resp := doJSON(t, srv, "/api/address/geocode", token, map[string]string{"lat": "55.7538", "lng": "37.6207"})
+	if addresses := decodeAddresses(t, resp); len(addresses) != 1 || addresses[0].Lon != 37.6208 {
+		t.Errorf("unexpected addresses %+v", addresses)
+	}
+}
+
+func TestRouterDetailLevel(t *testing.T) {
+	srv, _, token := newTestRouter(t)
+
+	resp := doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: "красная"})
+	if addresses := decodeAddresses(t, resp); len(addresses) != 1 || addresses[0].Details != nil {
+		t.Errorf("expected basic addresses by default, got %+v", addresses)
+	}
+
+	// Ответ берётся из кэша, но подробности в нём сохранены
+	resp = doJSON(t, srv, "/api/address/search?detail=full", token, entity.RequestAddressSearch{Query: "красная"})
+	addresses := decodeAddresses(t, resp)
+	if len(addresses) != 1 || addresses[0].Details == nil {
+		t.Fatalf("expected full addresses, got %+v", addresses)
+	}
+	if details := addresses[0].Details; details.PostalCode != "109012" || details.StreetWithType != "пл Красная" || details.QcGeo != "0" {
+		t.Errorf("unexpected details %+v", details)
+	}
+
+	if resp := doJSON(t, srv, "/api/address/geocode?detail=everything", token, entity.GeocodeRequest{Lat: 55.75, Lng: 37.62}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for unknown detail level, got %d", resp.StatusCode)
+	}
+}
+
+func TestRouterBatch(t *testing.T) {
+	srv, _, token := newTestRouter(t)
+
+	resp := doJSON(t, srv, "/api/address/search/batch", token, map[string]interface{}{
+		"items": []interface{}{
+			map[string]string{"query": "невский"},
+			map[string]int{"query": 42},
+			map[string]string{"query": "красная"},
+		},
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	var body struct {
+		Results []struct {
+			Addresses []*entity.Address `json:"addresses"`
+			Error     *struct {
+				Status int    `json:"status"`
+				Field  string `json:"field"`
+			} `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(body.Results))
+	}
+	if len(body.Results[0].Addresses) != 1 || body.Results[0].Addresses[0].Street != "Невский" {
+		t.Errorf("unexpected first result %+v", body.Results[0])
+	}
+	if e := body.Results[1].Error; e == nil || e.Status != http.StatusBadRequest || e.Field != "query" {
+		t.Errorf("expected field error for second item, got %+v", e)
+	}
+	if len(body.Results[2].Addresses) != 1 || body.Results[2].Addresses[0].Street != "Красная" {
+		t.Errorf("unexpected third result %+v", body.Results[2])
+	}
+
+	resp = doJSON(t, srv, "/api/address/geocode/batch", token, map[string]interface{}{
+		"items": []entity.GeocodeRequest{{Lat: 55.7538, Lng: 37.6207}, {Lat: 999, Lng: 0}},
+	})
+	body.Results = nil
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Results) != 2 || len(body.Results[0].Addresses) != 1 || body.Results[1].Error == nil || body.Results[1].Error.Field != "lat" {
+		t.Errorf("unexpected geocode batch results %+v", body.Results)
+	}
+
+	if resp := doJSON(t, srv, "/api/address/search/batch", token, map[string]interface{}{"items": []interface{}{}}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for empty batch, got %d", resp.StatusCode)
+	}
+}
+
+// slowProvider отвечает на поиск с задержкой
+type slowProvider struct {
+	entity.GeoProvider
+	delay time.Duration
+}
+
+func (p slowProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	time.Sleep(p.delay)
+	return entity.ResponseAddresses{Addresses: []*entity.Address{{City: query}}}, nil
+}
+
+func TestRouterBatchOutlivesWriteTimeout(t *testing.T) {
+	usecase.SetTokenFile(filepath.Join(t.TempDir(), "tokens.json"))
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	// Токен выдаётся в обход сервера: bcrypt под -race идёт дольше короткого WriteTimeout
+	user := entity.User{Username: t.Name(), Password: "password"}
+	if err := usecase.Register(&user); err != nil {
+		t.Fatal(err)
+	}
+	token, err := usecase.Login(&user)
+	if err != nil || token == "" {
+		t.Fatalf("login: %q, %v", token, err)
+	}
+
+	srv := httptest.NewUnstartedServer(Router(repository.NewResponder(zap.NewNop()), slowProvider{delay: time.Second}, adapter.NewMemoryCache(cache)))
+	// Пакет идёт дольше WriteTimeout сервера, рассчитанного на одиночные запросы
+	srv.Config.WriteTimeout = 500 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp := doJSON(t, srv, "/api/address/search/batch", token, map[string]interface{}{
+		"items": []map[string]string{{"query": "slow-batch-1"}, {"query": "slow-batch-2"}},
+	})
+	var body batchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("expected the batch response to be written, got %v", err)
+	}
+	if len(body.Results) != 2 || len(body.Results[1].Addresses) != 1 {
+		t.Errorf("unexpected results %+v", body.Results)
+	}
+}
+
+func TestRouterStream(t *testing.T) {
+	srv, _, token := newTestRouter(t)
+
+	body, input := io.Pipe()
+	req, err := http.NewRequest("POST", srv.URL+"/api/address/search/stream", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	type streamLine struct {
+		Index     int               `json:"index"`
+		Addresses []*entity.Address `json:"addresses"`
+		Error     *struct {
+			Status int    `json:"status"`
+			Field  string `json:"field"`
+		} `json:"error"`
+	}
+	out := json.NewDecoder(resp.Body)
+	next := func() streamLine {
+		t.Helper()
+		var line streamLine
+		if err := out.Decode(&line); err != nil {
+			t.Fatal(err)
+		}
+		return line
+	}
+
+	// Результат приходит до того, как клиент закончил отправлять запросы
+	io.WriteString(input, `{"query":"невский"}`+"\n")
+	if line := next(); line.Index != 0 || len(line.Addresses) != 1 || line.Addresses[0].Street != "Невский" {
+		t.Errorf("unexpected first line %+v", line)
+	}
+	io.WriteString(input, "\n"+`{"query":42}`+"\n")
+	if line := next(); line.Index != 1 || line.Error == nil || line.Error.Status != http.StatusBadRequest || line.Error.Field != "query" {
+		t.Errorf("expected a field error for the second request, got %+v", line)
+	}
+	io.WriteString(input, `{"query":"красная"}`+"\n"+`not json`+"\n")
+	input.Close()
+
+	lines := map[int]streamLine{}
+	for out.More() {
+		line := next()
+		lines[line.Index] = line
+	}
+	if len(lines) != 2 || len(lines[2].Addresses) != 1 || lines[3].Error == nil || lines[3].Error.Status != http.StatusBadRequest {
+		t.Errorf("unexpected last lines %+v", lines)
+	}
+}
+
+func TestRouterJobs(t *testing.T) {
+	srv, fake, token := newTestRouter(t)
+	other := login(t, srv, t.Name()+"-other")
+
+	fake.SetDelay(100 * time.Millisecond)
+	input := "query\nмосква тверская\nневский\n"
+	resp := doRequest(t, srv, "POST", "/api/jobs", token, "text/csv", strings.NewReader(input))
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	var job entity.Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Total != 2 || resp.Header.Get("Location") != "/api/jobs/"+job.ID {
+		t.Fatalf("unexpected job %+v, location %q", job, resp.Header.Get("Location"))
+	}
+	if resp := doRequest(t, srv, "GET", "/api/jobs/"+job.ID+"/results", token, "", nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 before the job is finished, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, srv, "GET", "/api/jobs/"+job.ID, other, "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for another user, got %d", resp.StatusCode)
+	}
+
+	if job = waitJob(t, srv, token, job); job.State != entity.JobDone || job.Processed != 2 || job.Failed != 0 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	resp = doRequest(t, srv, "GET", "/api/jobs/"+job.ID+"/results?format=csv", token, "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+	rows, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][1] != "москва тверская" || rows[1][2] != "ok" || rows[1][6] != "Тверская" {
+		t.Errorf("unexpected csv %v", rows)
+	}
+
+	resp = doRequest(t, srv, "GET", "/api/jobs/"+job.ID+"/results", token, "", nil)
+	var results []entity.JobResult
+	for dec := json.NewDecoder(resp.Body); dec.More(); {
+		var result entity.JobResult
+		if err := dec.Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, result)
+	}
+	if len(results) != 2 || results[1].Addresses[0].Street != "Невский" {
+		t.Errorf("unexpected ndjson results %+v", results)
+	}
+
+	resp = doRequest(t, srv, "GET", "/api/jobs", other, "", nil)
+	var list []entity.Job
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil || len(list) != 0 {
+		t.Errorf("expected no jobs for another user, got %+v %v", list, err)
+	}
+
+	// После перезапуска пользователей нет, и имя может занять кто угодно: задания прежнего
+	// владельца ему не достаются
+	delete(entity.Users, t.Name())
+	squatter := login(t, srv, t.Name())
+	if resp := doRequest(t, srv, "GET", "/api/jobs/"+job.ID+"/results", squatter, "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for a re-registered username, got %d", resp.StatusCode)
+	}
+
+	// Некорректный элемент не отклоняет задание, а завершается с ошибкой
+	fake.SetDelay(0)
+	input = `{"lat":55.7538,"lng":37.6207}` + "\n" + `{"lat":999,"lng":0}` + "\n"
+	resp = doRequest(t, srv, "POST", "/api/jobs?kind=geocode&format=ndjson", token, "", strings.NewReader(input))
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	if job = waitJob(t, srv, token, job); job.State != entity.JobDone || job.Processed != 2 || job.Failed != 1 {
+		t.Errorf("unexpected geocode job %+v", job)
+	}
+	if resp := doRequest(t, srv, "POST", "/api/jobs?kind=geocode", token, "text/csv", strings.NewReader("query\nмосква\n")); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for geocode without coordinates, got %d", resp.StatusCode)
+	}
+}
+
+// waitJob опрашивает статус задания, пока оно не завершится
+func waitJob(t *testing.T, srv *httptest.Server, token string, job entity.Job) entity.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.State.Finished() {
+		if time.Now().After(deadline) {
+			t.Fatalf("job is still %s", job.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+		resp := doRequest(t, srv, "GET", "/api/jobs/"+job.ID, token, "", nil)
+		if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return job
+}
+
+func TestRouterCacheAdmin(t *testing.T) {
+	srv, _, token := newTestRouter(t)
+	adminUser := entity.User{Username: t.Name() + "-admin", Password: "admin-password"}
+	hash, err := bcrypt.GenerateFromPassword([]byte(adminUser.Password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := usecase.AddAdmin(adminUser.Username, string(hash)); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(entity.AdminUsers, adminUser.Username)
+	defer delete(entity.Users, adminUser.Username)
+
+	// Имя администратора нельзя занять регистрацией
+	squatter := entity.User{Username: adminUser.Username, Password: "password"}
+	if resp := doJSON(t, srv, "/api/register", "", squatter); resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 when registering an admin name, got %d", resp.StatusCode)
+	}
+	if resp := doJSON(t, srv, "/api/login", "", squatter); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the admin password to be kept, got %d", resp.StatusCode)
+	}
+	admin := signIn(t, srv, adminUser)
+
+	if resp := doRequest(t, srv, "GET", "/api/admin/cache/keys", token, "", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin user, got %d", resp.StatusCode)
+	}
+	if resp := doRequest(t, srv, "DELETE", "/api/admin/cache", token, "", nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403 for a non-admin flush, got %d", resp.StatusCode)
+	}
+
+	for _, q := range []string{"невский", "красная", "москва тверская"} {
+		doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: q})
+	}
+	keys := func() []string {
+		t.Helper()
+		var keys []string
+		resp := doRequest(t, srv, "GET", "/api/admin/cache/keys?prefix=search:", admin, "", nil)
+		if err := json.NewDecoder(resp.Body).Decode(&keys); err != nil {
+			t.Fatal(err)
+		}
+		return keys
+	}
+	deleted := func(query string) int {
+		t.Helper()
+		resp := doRequest(t, srv, "DELETE", "/api/admin/cache/keys?"+query, admin, "", nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("delete %s: unexpected status %d", query, resp.StatusCode)
+		}
+		var body struct {
+			Data struct {
+				Deleted int `json:"deleted"`
+			} `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Data.Deleted
+	}
+
+	if got := keys(); len(got) != 3 {
+		t.Fatalf("expected 3 search keys, got %v", got)
+	}
+	key := url.QueryEscape("search:невский")
+	if n := deleted("key=" + key); n != 1 {
+		t.Errorf("expected 1 deleted key, got %d", n)
+	}
+	if n := deleted("key=" + key); n != 0 {
+		t.Errorf("expected a missing key to report 0 deleted, got %d", n)
+	}
+	if n := deleted("prefix=" + url.QueryEscape("search:красная")); n != 1 {
+		t.Errorf("expected 1 key deleted by prefix, got %d", n)
+	}
+	if resp := doRequest(t, srv, "DELETE", "/api/admin/cache/keys", admin, "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 without key or prefix, got %d", resp.StatusCode)
+	}
+
+	if resp := doRequest(t, srv, "DELETE", "/api/admin/cache", admin, "", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("flush: unexpected status %d", resp.StatusCode)
+	}
+	if got := keys(); len(got) != 0 {
+		t.Errorf("expected an empty cache after flush, got %v", got)
+	}
+}
+
+func TestRouterUpstreamFailures(t *testing.T) {
+	srv, fake, token := newTestRouter(t)
+
+	// Один сбой DaData закрывается повтором
+	fake.FailNext(dadatafake.FailInternal, 1)
+	if resp := doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: "невский"}); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected retry to hide a single failure, got %d", resp.StatusCode)
+	}
+
+	// Ошибки кэшируются по ключу, поэтому у каждого случая свой запрос
+	for _, tc := range []struct {
+		failure dadatafake.Failure
+		query   string
+		status  int
+	}{
+		{dadatafake.FailUnauthorized, "москва 1", http.StatusBadGateway},
+		{dadatafake.FailForbidden, "москва 2", http.StatusServiceUnavailable},
+		{dadatafake.FailTooManyRequests, "москва 3", http.StatusTooManyRequests},
+		{dadatafake.FailInternal, "москва 4", http.StatusBadGateway},
+		{dadatafake.FailMalformed, "москва 5", http.StatusInternalServerError},
+	} {
+		fake.SetFailure(tc.failure)
+		resp := doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: tc.query})
+		if resp.StatusCode != tc.status {
+			t.Errorf("failure %d: expected %d, got %d", tc.failure, tc.status, resp.StatusCode)
+		}
+		// Повтор отдаётся из негативного кэша с тем же статусом
+		fake.SetFailure(dadatafake.FailNone)
+		if resp := doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: tc.query}); resp.StatusCode != tc.status {
+			t.Errorf("failure %d: expected cached %d, got %d", tc.failure, tc.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestRouterUpstreamTimeout(t *testing.T) {
+	srv, fake, token := newTestRouter(t)
+	usecase.SetTimeouts(usecase.Timeouts{Geocode: 50 * time.Millisecond, Search: 50 * time.Millisecond})
+	defer usecase.SetTimeouts(usecase.Timeouts{Geocode: 5 * time.Second, Search: 5 * time.Second})
+	fake.SetDelay(time.Second)
+
+	start := time.Now()
+	resp := doJSON(t, srv, "/api/address/search", token, entity.RequestAddressSearch{Query: "москва медленно"})
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("expected 504, got %d", resp.StatusCode)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected timeout to cut the request, took %s", time.Since(start))
+	}
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+// streamTimeout - сколько ждать очередной строки запроса и приёма очередного результата.
+// Таймауты сервера рассчитаны на одиночные запросы, поток продлевает их после каждой строки.
+const streamTimeout = 30 * time.Second
+
+// maxStreamLine - наибольшая длина строки запроса потока
+const maxStreamLine = 1 << 20
+
+// streamItem - строка ответа потока. Index - номер запроса в теле (пустые строки не считаются):
+// результаты идут в порядке готовности, а не в порядке запросов.
+type streamItem struct {
+	Index int `json:"index"`
+	batchItem
+}
+
+func geocodeStreamHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleStream(resp, w, r, func(ctx context.Context, in <-chan usecase.StreamRequest[entity.GeocodeRequest], out chan<- usecase.StreamResult) {
+			usecase.HandleGeocodeStream(ctx, in, out, geoService, cache)
+		})
+	}
+}
+
+func searchStreamHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleStream(resp, w, r, func(ctx context.Context, in <-chan usecase.StreamRequest[entity.RequestAddressSearch], out chan<- usecase.StreamResult) {
+			usecase.HandleSearchStream(ctx, in, out, geoService, cache)
+		})
+	}
+}
+
+// handleStream принимает NDJSON-запросы - по запросу в формате одиночного на строку -
+// и отвечает NDJSON-результатами, отправляя каждый сразу. Ошибка строки не прерывает поток,
+// а приходит в её результате. Чтение тела идёт не быстрее, чем клиент принимает результаты.
+// Поддерживает ?detail=basic|full.
+func handleStream[T any](resp entity.Responder, w http.ResponseWriter, r *http.Request, run func(ctx context.Context, in <-chan usecase.StreamRequest[T], out chan<- usecase.StreamResult)) {
+	detail, err := entity.ParseDetailLevel(r.URL.Query().Get("detail"))
+	if err != nil {
+		resp.ErrorBadRequest(w, err)
+		return
+	}
+	rc := http.NewResponseController(w)
+	// Без этого HTTP/1.1-сервер перестаёт читать тело запроса, как только начат ответ
+	rc.EnableFullDuplex()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	in := make(chan usecase.StreamRequest[T])
+	out := make(chan usecase.StreamResult)
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		readStream(ctx, r.Body, rc, in)
+	}()
+	go run(ctx, in, out)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+	enc := json.NewEncoder(w)
+	for result := range out {
+		item := streamItem{Index: result.Index}
+		if result.Err != nil {
+			item.Error = newBatchError(result.Err)
+		} else {
+			geo := result.Geo.WithDetail(detail)
+			item.Addresses, item.Provider = geo.Addresses, geo.Provider
+		}
+		rc.SetWriteDeadline(time.Now().Add(streamTimeout))
+		err := enc.Encode(item)
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			// Клиент ушёл: прерываем чтение тела и дожидаемся исполнителей
+			cancel()
+			rc.SetReadDeadline(time.Now())
+			for range out {
+			}
+			break
+		}
+	}
+	// Тело нельзя читать после выхода из обработчика
+	<-readDone
+}
+
+// readStream разбирает строки тела в in и закрывает его в конце тела, при ошибке чтения
+// или отмене ctx. Ошибка чтения отдаётся последним запросом, чтобы клиент узнал, где поток оборвался.
+func readStream[T any](ctx context.Context, body io.Reader, rc *http.ResponseController, in chan<- usecase.StreamRequest[T]) {
+	defer close(in)
+	send := func(req usecase.StreamRequest[T]) bool {
+		select {
+		case in <- req:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxStreamLine)
+	index := 0
+	for ctx.Err() == nil {
+		rc.SetReadDeadline(time.Now().Add(streamTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		req := usecase.StreamRequest[T]{Index: index}
+		if err := json.Unmarshal(line, &req.Req); err != nil {
+			req.Err = streamLineError(err)
+		}
+		index++
+		if !send(req) {
+			return
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		send(usecase.StreamRequest[T]{Index: index, Err: &entity.FieldError{Field: "body", Message: err.Error()}})
+	}
+}
+
+// streamLineError - ошибка разбора строки; всегда *entity.FieldError, чтобы клиент получил 400
+func streamLineError(err error) error {
+	err = fieldError(err)
+	var fieldErr *entity.FieldError
+	if errors.As(err, &fieldErr) {
+		return err
+	}
+	return &entity.FieldError{Field: "body", Message: err.Error()}
+}
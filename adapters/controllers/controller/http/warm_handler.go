@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+// warmState хранит отчёт текущего или последнего прогрева кэша
+type warmState struct {
+	mu     sync.Mutex
+	report usecase.WarmReport
+}
+
+func (s *warmState) set(report usecase.WarmReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report = report
+}
+
+func (s *warmState) get() usecase.WarmReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.report
+}
+
+// warmStartHandler принимает список прогрева в теле запроса (см. usecase.ParseWarmList)
+// и запускает прогрев в фоне. Параметры: ?concurrency=4&rate=10; concurrency ограничена
+// usecase.MaxWarmConcurrency. Пока идёт прогрев, новый не запускается: 409.
+func warmStartHandler(resp entity.Responder, geoService entity.GeoProvider, cache entity.Cache, state *warmState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, err := usecase.ParseWarmList(r.Body)
+		if err != nil {
+			resp.ErrorBadRequest(w, err)
+			return
+		}
+		opts := usecase.WarmOptions{Concurrency: 4}
+		if v := r.URL.Query().Get("concurrency"); v != "" {
+			if opts.Concurrency, err = strconv.Atoi(v); err != nil {
+				resp.ErrorBadRequest(w, err)
+				return
+			}
+		}
+		if v := r.URL.Query().Get("rate"); v != "" {
+			if opts.Rate, err = strconv.ParseFloat(v, 64); err != nil {
+				resp.ErrorBadRequest(w, err)
+				return
+			}
+		}
+
+		state.mu.Lock()
+		if state.report.Running {
+			state.mu.Unlock()
+			resp.ErrorConflict(w, errors.New("cache warm-up is already running"))
+			return
+		}
+		state.report = usecase.WarmReport{Total: len(items), Running: true}
+		state.mu.Unlock()
+
+		opts.Progress = state.set
+		go func() {
+			state.set(usecase.Warm(context.Background(), items, geoService, cache, opts))
+		}()
+		resp.OutputJSON(w, state.get())
+	}
+}
+
+func warmStatusHandler(resp entity.Responder, state *warmState) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp.OutputJSON(w, state.get())
+	}
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// earthRadius - средний радиус Земли в метрах
+const earthRadius = 6371000
+
+// kdPoint - адрес с координатами на единичной сфере. Хордовое расстояние между такими
+// точками монотонно по расстоянию по поверхности, поэтому k-d дерево в трёх измерениях
+// ищет ближайшие точки без искажений у полюсов и у 180-го меридиана.
+type kdPoint struct {
+	xyz     [3]float64
+	address *entity.Address
+}
+
+func newKDPoint(lat, lng float64, address *entity.Address) kdPoint {
+	return kdPoint{xyz: toUnitSphere(lat, lng), address: address}
+}
+
+func toUnitSphere(lat, lng float64) [3]float64 {
+	phi := lat * math.Pi / 180
+	lambda := lng * math.Pi / 180
+	return [3]float64{math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)}
+}
+
+// chordForDistance переводит расстояние по поверхности в метрах в длину хорды единичной сферы
+func chordForDistance(meters float64) float64 {
+	return 2 * math.Sin(math.Min(meters/earthRadius, math.Pi)/2)
+}
+
+type kdNode struct {
+	point       kdPoint
+	axis        int
+	left, right *kdNode
+}
+
+// buildKDTree строит сбалансированное дерево, деля точки по медиане. points переупорядочивается.
+func buildKDTree(points []kdPoint, depth int) *kdNode {
+	if len(points) == 0 {
+		return nil
+	}
+	axis := depth % 3
+	sort.Slice(points, func(i, j int) bool { return points[i].xyz[axis] < points[j].xyz[axis] })
+	mid := len(points) / 2
+	return &kdNode{
+		point: points[mid],
+		axis:  axis,
+		left:  buildKDTree(points[:mid], depth+1),
+		right: buildKDTree(points[mid+1:], depth+1),
+	}
+}
+
+// kdMatch - найденная точка и квадрат хордового расстояния до неё
+type kdMatch struct {
+	point kdPoint
+	dist2 float64
+}
+
+// kdMatches - max-куча по расстоянию: в вершине самая дальняя из лучших
+type kdMatches []kdMatch
+
+func (m kdMatches) Len() int            { return len(m) }
+func (m kdMatches) Less(i, j int) bool  { return m[i].dist2 > m[j].dist2 }
+func (m kdMatches) Swap(i, j int)       { m[i], m[j] = m[j], m[i] }
+func (m *kdMatches) Push(x interface{}) { *m = append(*m, x.(kdMatch)) }
+func (m *kdMatches) Pop() interface{} {
+	old := *m
+	x := old[len(old)-1]
+	*m = old[:len(old)-1]
+	return x
+}
+
+// nearest возвращает до k ближайших к target точек в пределах хорды maxChord, от ближней к дальней
+func (n *kdNode) nearest(target [3]float64, k int, maxChord float64) []kdMatch {
+	if n == nil || k <= 0 {
+		return nil
+	}
+	matches := make(kdMatches, 0, k)
+	n.search(target, k, maxChord*maxChord, &matches)
+	sort.Slice(matches, func(i, j int) bool { return matches[i].dist2 < matches[j].dist2 })
+	return matches
+}
+
+func (n *kdNode) search(target [3]float64, k int, maxDist2 float64, matches *kdMatches) {
+	if n == nil {
+		return
+	}
+	var dist2 float64
+	for i := range target {
+		d := target[i] - n.point.xyz[i]
+		dist2 += d * d
+	}
+	if dist2 <= maxDist2 {
+		if matches.Len() < k {
+			heap.Push(matches, kdMatch{point: n.point, dist2: dist2})
+		} else if dist2 < (*matches)[0].dist2 {
+			(*matches)[0] = kdMatch{point: n.point, dist2: dist2}
+			heap.Fix(matches, 0)
+		}
+	}
+
+	diff := target[n.axis] - n.point.xyz[n.axis]
+	near, far := n.left, n.right
+	if diff > 0 {
+		near, far = n.right, n.left
+	}
+	near.search(target, k, maxDist2, matches)
+	// Дальнюю ветку смотрим, только если разделяющая плоскость ближе текущей границы
+	bound := maxDist2
+	if matches.Len() == k && (*matches)[0].dist2 < bound {
+		bound = (*matches)[0].dist2
+	}
+	if diff*diff <= bound {
+		far.search(target, k, maxDist2, matches)
+	}
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// ProviderConfig - настройки геопровайдера, общие для всех реализаций.
+// Каждая реализация берёт только нужные ей поля.
+type ProviderConfig struct {
+	APIKey    string
+	SecretKey string
+	// BaseURL переопределяет адрес API провайдера
+	BaseURL string
+	// UserAgent обязателен для публичного Nominatim
+	UserAgent string
+	// HTTPClient заменяет http.DefaultClient, например для записи и воспроизведения фикстур
+	HTTPClient *http.Client
+	// Breaker - настройки автомата защиты, FailureThreshold == 0 отключает его
+	Breaker adapter.BreakerConfig
+	// Retry - политика повторов, нулевое значение - DefaultRetryPolicy
+	Retry RetryPolicy
+	// Limit - ограничение частоты и суточная квота, нулевое значение их отключает
+	Limit LimitConfig
+	// Timeout - предел одного вызова провайдера в цепочке, после него запрос уходит
+	// следующему; 0 - без собственного предела
+	Timeout time.Duration
+	// DataFile - файл с адресами для офлайн-провайдера (CSV или GeoJSON)
+	DataFile string
+	// Nearest и Radius (в метрах) ограничивают ответ офлайн-провайдера, 0 - по умолчанию
+	Nearest int
+	Radius  float64
+}
+
+// ProviderFactory создаёт провайдер по настройкам
+type ProviderFactory func(cfg ProviderConfig) (entity.GeoProvider, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]ProviderFactory)
+)
+
+func init() {
+	RegisterProvider("dadata", func(cfg ProviderConfig) (entity.GeoProvider, error) {
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("dadata: api key is required")
+		}
+		var opts []GeoRepoOption
+		if cfg.BaseURL != "" {
+			opts = append(opts, WithBaseURL(cfg.BaseURL))
+		}
+		if cfg.HTTPClient != nil {
+			opts = append(opts, WithHTTPClient(cfg.HTTPClient))
+		}
+		if cfg.Retry != (RetryPolicy{}) {
+			opts = append(opts, WithRetryPolicy(cfg.Retry))
+		}
+		repo, err := NewGeoService(cfg.APIKey, cfg.SecretKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+	RegisterProvider("nominatim", func(cfg ProviderConfig) (entity.GeoProvider, error) {
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = nominatimBaseURL
+		}
+		repo := NewNominatimService(baseURL, cfg.UserAgent)
+		if cfg.HTTPClient != nil {
+			repo.client = cfg.HTTPClient
+		}
+		return repo, nil
+	})
+	RegisterProvider("offline", func(cfg ProviderConfig) (entity.GeoProvider, error) {
+		if cfg.DataFile == "" {
+			return nil, fmt.Errorf("offline: data file is required")
+		}
+		var opts []OfflineOption
+		if cfg.Nearest > 0 {
+			opts = append(opts, WithOfflineNearest(cfg.Nearest))
+		}
+		if cfg.Radius > 0 {
+			opts = append(opts, WithOfflineRadius(cfg.Radius))
+		}
+		return NewOfflineService(cfg.DataFile, opts...)
+	})
+}
+
+// RegisterProvider делает провайдер доступным для NewProvider под именем name
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[name] = factory
+}
+
+// NewProvider создаёт зарегистрированный провайдер по имени, например из конфигурации деплоя
+func NewProvider(name string, cfg ProviderConfig) (entity.GeoProvider, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown geo provider %q, available: %v", name, Providers())
+	}
+	return factory(cfg)
+}
+
+// Providers возвращает имена зарегистрированных провайдеров
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
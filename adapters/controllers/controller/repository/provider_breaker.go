@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// BreakerProvider защищает провайдер отдельным автоматом на каждый метод,
+// чтобы сбой геокодирования не отключал поиск и наоборот
+type BreakerProvider struct {
+	name     string
+	provider entity.GeoProvider
+	breakers map[string]*adapter.CircuitBreaker
+}
+
+var (
+	_ entity.GeoProvider     = (*BreakerProvider)(nil)
+	_ entity.BreakerReporter = (*BreakerProvider)(nil)
+	_ entity.QuotaReporter   = (*BreakerProvider)(nil)
+)
+
+func NewBreakerProvider(name string, provider entity.GeoProvider, cfg adapter.BreakerConfig) *BreakerProvider {
+	breakers := make(map[string]*adapter.CircuitBreaker)
+	for _, endpoint := range []string{"address", "geocode", "search", "geocode_raw"} {
+		breakers[endpoint] = adapter.NewCircuitBreaker(cfg)
+	}
+	return &BreakerProvider{name: name, provider: provider, breakers: breakers}
+}
+
+func (b *BreakerProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	return guard(b, "address", func() (entity.ResponseAddresses, error) {
+		return b.provider.GetGeoCoordinatesAddress(ctx, query)
+	})
+}
+
+func (b *BreakerProvider) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	return guard(b, "geocode", func() (entity.ResponseAddresses, error) {
+		return b.provider.GetGeoCoordinatesGeocode(ctx, lat, lng)
+	})
+}
+
+func (b *BreakerProvider) AddressSearch(ctx context.Context, input string) ([]*entity.Address, error) {
+	return guard(b, "search", func() ([]*entity.Address, error) {
+		return b.provider.AddressSearch(ctx, input)
+	})
+}
+
+func (b *BreakerProvider) GeoCode(ctx context.Context, lat, lng string) ([]*entity.Address, error) {
+	return guard(b, "geocode_raw", func() ([]*entity.Address, error) {
+		return b.provider.GeoCode(ctx, lat, lng)
+	})
+}
+
+func (b *BreakerProvider) BreakerStates() map[string]string {
+	states := make(map[string]string, len(b.breakers))
+	for endpoint, breaker := range b.breakers {
+		states[b.name+"/"+endpoint] = breaker.State().String()
+	}
+	return states
+}
+
+// Close закрывает обёрнутый провайдер, если ему есть что сохранить
+func (b *BreakerProvider) Close() error {
+	if closer, ok := b.provider.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Quotas отдаёт лимиты обёрнутого провайдера, если они есть
+func (b *BreakerProvider) Quotas() []entity.QuotaStatus {
+	if reporter, ok := b.provider.(entity.QuotaReporter); ok {
+		return reporter.Quotas()
+	}
+	return nil
+}
+
+// guard пропускает вызов через автомат endpoint. Ошибкой провайдера считается то же,
+// что и для FailoverProvider: таймауты, сетевые ошибки, 5xx и квота.
+// Отказы клиентских лимитов (LimitedProvider) провайдера не характеризуют и не считаются,
+// как и вызовы, отменённые клиентом: ни ошибкой, ни успехом.
+func guard[T any](b *BreakerProvider, endpoint string, call func() (T, error)) (T, error) {
+	breaker := b.breakers[endpoint]
+	if err := breaker.Allow(); err != nil {
+		adapter.DefaultMetrics.Inc("breaker." + b.name + "." + endpoint + ".rejected")
+		var zero T
+		return zero, err
+	}
+	res, err := call()
+	if errors.Is(err, context.Canceled) {
+		breaker.Release()
+		return res, err
+	}
+	failure := err != nil && shouldFailover(err) && !limitError(err)
+	breaker.Record(failure)
+	if failure && breaker.State() == adapter.BreakerOpen {
+		adapter.DefaultMetrics.Inc("breaker." + b.name + "." + endpoint + ".opened")
+	}
+	return res, err
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestBreakerProviderIgnoresCanceled(t *testing.T) {
+	stub := &stubProvider{}
+	b := NewBreakerProvider("dadata", stub, adapter.BreakerConfig{FailureThreshold: 2})
+	call := func(err error) {
+		stub.err = err
+		b.GetGeoCoordinatesAddress(context.Background(), "query")
+	}
+
+	// Массовый уход клиентов не размыкает цепь
+	for range 5 {
+		call(&url.Error{Op: "Post", URL: "http://dadata", Err: context.Canceled})
+	}
+	if state := b.BreakerStates()["dadata/address"]; state != adapter.BreakerClosed.String() {
+		t.Fatalf("expected closed breaker after canceled calls, got %s", state)
+	}
+
+	// и не сбрасывает счётчик настоящих ошибок
+	call(entity.NewUpstreamError("dadata", 502, nil))
+	call(context.Canceled)
+	call(entity.NewUpstreamError("dadata", 502, nil))
+	if state := b.BreakerStates()["dadata/address"]; state != adapter.BreakerOpen.String() {
+		t.Errorf("expected open breaker after two upstream failures, got %s", state)
+	}
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// NamedProvider - провайдер с именем для метрик и заголовка X-Geo-Provider.
+// Timeout ограничивает один вызов провайдера, чтобы у следующих осталось время на ответ;
+// 0 - вызов ограничен только дедлайном запроса.
+type NamedProvider struct {
+	Name     string
+	Provider entity.GeoProvider
+	Timeout  time.Duration
+}
+
+// FailoverProvider опрашивает провайдеров по порядку и переходит к следующему при таймаутах,
+// сетевых ошибках, 5xx и исчерпанной квоте. Пустой успешный ответ - это ответ, а не отказ.
+type FailoverProvider struct {
+	providers []NamedProvider
+}
+
+var (
+	_ entity.GeoProvider     = (*FailoverProvider)(nil)
+	_ entity.BreakerReporter = (*FailoverProvider)(nil)
+	_ entity.QuotaReporter   = (*FailoverProvider)(nil)
+)
+
+func NewFailoverProvider(providers ...NamedProvider) *FailoverProvider {
+	return &FailoverProvider{providers: providers}
+}
+
+// NewProviderChain создаёт провайдеры по именам из реестра и собирает из них FailoverProvider.
+// Провайдеры с заданными cfg.Limit оборачиваются в LimitedProvider, с cfg.Breaker - в BreakerProvider.
+// Автомат стоит снаружи, чтобы при разомкнутой цепи не тратить токены и квоту.
+func NewProviderChain(names []string, configFor func(name string) ProviderConfig) (*FailoverProvider, error) {
+	var chain []NamedProvider
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		cfg := configFor(name)
+		provider, err := NewProvider(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Limit.Rate > 0 || cfg.Limit.DailyQuota > 0 {
+			if provider, err = NewLimitedProvider(name, provider, cfg.Limit); err != nil {
+				return nil, err
+			}
+		}
+		if cfg.Breaker.FailureThreshold > 0 {
+			provider = NewBreakerProvider(name, provider, cfg.Breaker)
+		}
+		chain = append(chain, NamedProvider{Name: name, Provider: provider, Timeout: cfg.Timeout})
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no geo providers configured")
+	}
+	return NewFailoverProvider(chain...), nil
+}
+
+func (f *FailoverProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	return f.try(ctx, func(ctx context.Context, p entity.GeoProvider) (entity.ResponseAddresses, error) {
+		return p.GetGeoCoordinatesAddress(ctx, query)
+	})
+}
+
+func (f *FailoverProvider) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	return f.try(ctx, func(ctx context.Context, p entity.GeoProvider) (entity.ResponseAddresses, error) {
+		return p.GetGeoCoordinatesGeocode(ctx, lat, lng)
+	})
+}
+
+func (f *FailoverProvider) AddressSearch(ctx context.Context, input string) ([]*entity.Address, error) {
+	geo, err := f.try(ctx, func(ctx context.Context, p entity.GeoProvider) (entity.ResponseAddresses, error) {
+		addresses, err := p.AddressSearch(ctx, input)
+		return entity.ResponseAddresses{Addresses: addresses}, err
+	})
+	return geo.Addresses, err
+}
+
+func (f *FailoverProvider) GeoCode(ctx context.Context, lat, lng string) ([]*entity.Address, error) {
+	geo, err := f.try(ctx, func(ctx context.Context, p entity.GeoProvider) (entity.ResponseAddresses, error) {
+		addresses, err := p.GeoCode(ctx, lat, lng)
+		return entity.ResponseAddresses{Addresses: addresses}, err
+	})
+	return geo.Addresses, err
+}
+
+// BreakerStates собирает состояния автоматов всех провайдеров цепочки
+func (f *FailoverProvider) BreakerStates() map[string]string {
+	states := make(map[string]string)
+	for _, np := range f.providers {
+		if reporter, ok := np.Provider.(entity.BreakerReporter); ok {
+			for name, state := range reporter.BreakerStates() {
+				states[name] = state
+			}
+		}
+	}
+	return states
+}
+
+// Quotas собирает лимиты всех провайдеров цепочки
+func (f *FailoverProvider) Quotas() []entity.QuotaStatus {
+	var quotas []entity.QuotaStatus
+	for _, np := range f.providers {
+		if reporter, ok := np.Provider.(entity.QuotaReporter); ok {
+			quotas = append(quotas, reporter.Quotas()...)
+		}
+	}
+	return quotas
+}
+
+// Close закрывает провайдеры цепочки, например сохраняет счётчики квот
+func (f *FailoverProvider) Close() error {
+	var errs []error
+	for _, np := range f.providers {
+		if closer, ok := np.Provider.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", np.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// try опрашивает провайдеров по очереди, пока не истёк ctx: после дедлайна следующему
+// провайдеру всё равно не успеть ответить. Зависший провайдер с Timeout прерывается
+// раньше, и запрос уходит следующему.
+func (f *FailoverProvider) try(ctx context.Context, call func(ctx context.Context, p entity.GeoProvider) (entity.ResponseAddresses, error)) (entity.ResponseAddresses, error) {
+	var errs []error
+	for _, np := range f.providers {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		geo, err := f.call(ctx, np, call)
+		if err == nil {
+			adapter.DefaultMetrics.Inc("provider." + np.Name + ".answered")
+			geo.Provider = np.Name
+			return geo, nil
+		}
+		// Клиент ушёл сам - провайдер тут ни при чём
+		if errors.Is(err, context.Canceled) {
+			return entity.ResponseAddresses{}, err
+		}
+		adapter.DefaultMetrics.Inc("provider." + np.Name + ".failed")
+		if !shouldFailover(err) {
+			return entity.ResponseAddresses{}, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", np.Name, err))
+	}
+	return entity.ResponseAddresses{}, fmt.Errorf("all geo providers failed: %w", errors.Join(errs...))
+}
+
+func (f *FailoverProvider) call(ctx context.Context, np NamedProvider, call func(ctx context.Context, p entity.GeoProvider) (entity.ResponseAddresses, error)) (entity.ResponseAddresses, error) {
+	if np.Timeout <= 0 {
+		return call(ctx, np.Provider)
+	}
+	ctx, cancel := context.WithTimeout(ctx, np.Timeout)
+	defer cancel()
+	return call(ctx, np.Provider)
+}
+
+// shouldFailover решает, стоит ли пробовать следующего провайдера после err
+func shouldFailover(err error) bool {
+	var upstream *entity.UpstreamError
+	if errors.As(err, &upstream) {
+		return upstream.Temporary() || upstream.Quota()
+	}
+	if limitError(err) {
+		return !errors.Is(err, entity.ErrStaleOnly)
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, entity.ErrCircuitOpen) {
+		return true
+	}
+	// *url.Error сам реализует net.Error, поэтому смотрим на исходную ошибку, как в retryable
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
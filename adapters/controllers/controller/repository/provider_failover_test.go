@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+type stubProvider struct {
+	entity.GeoProvider
+	geo   entity.ResponseAddresses
+	err   error
+	calls int
+}
+
+func (s *stubProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	s.calls++
+	return s.geo, s.err
+}
+
+func TestFailoverProvider(t *testing.T) {
+	answer := entity.ResponseAddresses{Addresses: []*entity.Address{{City: "Москва"}}}
+
+	tests := []struct {
+		name         string
+		primaryErr   error
+		primaryGeo   entity.ResponseAddresses
+		wantProvider string
+		wantErr      bool
+	}{
+		{name: "5xx falls through", primaryErr: entity.NewUpstreamError("dadata", 502, nil), wantProvider: "nominatim"},
+		{name: "quota falls through", primaryErr: entity.NewUpstreamError("dadata", 429, nil), wantProvider: "nominatim"},
+		{name: "empty result is an answer", wantProvider: "dadata"},
+		{name: "4xx does not fall through", primaryErr: entity.NewUpstreamError("dadata", 400, nil), wantErr: true},
+		{name: "unknown error does not fall through", primaryErr: errors.New("boom"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubProvider{geo: tt.primaryGeo, err: tt.primaryErr}
+			secondary := &stubProvider{geo: answer}
+			f := NewFailoverProvider(NamedProvider{Name: "dadata", Provider: primary}, NamedProvider{Name: "nominatim", Provider: secondary})
+
+			geo, err := f.GetGeoCoordinatesAddress(context.Background(), "query")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				if secondary.calls != 0 {
+					t.Error("expected secondary provider not to be called")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if geo.Provider != tt.wantProvider {
+				t.Errorf("expected provider %s, got %s", tt.wantProvider, geo.Provider)
+			}
+		})
+	}
+}
+
+func TestFailoverProviderCanceled(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "canceled", err: context.Canceled},
+		// Так отмену возвращает http.Client
+		{name: "url error", err: &url.Error{Op: "Get", URL: "http://dadata", Err: context.Canceled}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubProvider{err: tt.err}
+			secondary := &stubProvider{}
+			f := NewFailoverProvider(NamedProvider{Name: "canceled-primary", Provider: primary}, NamedProvider{Name: "canceled-secondary", Provider: secondary})
+			failed := adapter.DefaultMetrics.Get("provider.canceled-primary.failed")
+
+			_, err := f.GetGeoCoordinatesAddress(context.Background(), "query")
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			if secondary.calls != 0 {
+				t.Error("expected secondary provider not to be called")
+			}
+			if got := adapter.DefaultMetrics.Get("provider.canceled-primary.failed"); got != failed {
+				t.Errorf("expected canceled call not to count as a provider failure, got %d", got-failed)
+			}
+		})
+	}
+}
+
+// hangingProvider отвечает, только когда истечёт контекст вызова
+type hangingProvider struct {
+	entity.GeoProvider
+}
+
+func (hangingProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	<-ctx.Done()
+	return entity.ResponseAddresses{}, ctx.Err()
+}
+
+func TestFailoverProviderTimeout(t *testing.T) {
+	secondary := &stubProvider{geo: entity.ResponseAddresses{Addresses: []*entity.Address{{City: "Москва"}}}}
+	f := NewFailoverProvider(
+		NamedProvider{Name: "dadata", Provider: hangingProvider{}, Timeout: 20 * time.Millisecond},
+		NamedProvider{Name: "nominatim", Provider: secondary},
+	)
+	// Общий дедлайн запроса больше, чем предел первого провайдера
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	geo, err := f.GetGeoCoordinatesAddress(ctx, "query")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if geo.Provider != "nominatim" || secondary.calls != 1 {
+		t.Errorf("expected the second provider to answer, got %q after %d calls", geo.Provider, secondary.calls)
+	}
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// LimitPolicy - что делать, когда лимит частоты или квота исчерпаны
+type LimitPolicy string
+
+const (
+	// LimitQueue ждёт свободный токен не дольше MaxWait. Исчерпанную квоту не ждёт.
+	LimitQueue LimitPolicy = "queue"
+	// LimitFailFast сразу отказывает, и FailoverProvider переходит к следующему провайдеру
+	LimitFailFast LimitPolicy = "fail"
+	// LimitStaleOnly сразу отказывает без перехода к другим провайдерам:
+	// клиенты получают только то, что есть в кэше, в том числе устаревшее
+	LimitStaleOnly LimitPolicy = "stale"
+)
+
+func ParseLimitPolicy(s string) (LimitPolicy, error) {
+	switch p := LimitPolicy(s); p {
+	case LimitQueue, LimitFailFast, LimitStaleOnly:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown limit policy %q", s)
+}
+
+// LimitConfig - ограничения на вызовы провайдера. Нулевые Rate и DailyQuota их отключают.
+type LimitConfig struct {
+	// Rate - вызовов в секунду, Burst - сколько можно сделать подряд
+	Rate  float64
+	Burst int
+	// DailyQuota - вызовов в сутки, счётчик хранится в QuotaFile
+	DailyQuota int
+	QuotaFile  string
+	// QuotaLocation - часовой пояс, в котором сбрасывается квота, по умолчанию локальный
+	QuotaLocation *time.Location
+	Policy        LimitPolicy
+	// MaxWait - сколько ждать токен при LimitQueue, по умолчанию 5 секунд
+	MaxWait time.Duration
+}
+
+// LimitedProvider ограничивает частоту вызовов провайдера и считает их суточную квоту
+type LimitedProvider struct {
+	name     string
+	provider entity.GeoProvider
+	cfg      LimitConfig
+	bucket   *adapter.TokenBucket
+	quota    *adapter.DailyQuota
+}
+
+var (
+	_ entity.GeoProvider   = (*LimitedProvider)(nil)
+	_ entity.QuotaReporter = (*LimitedProvider)(nil)
+)
+
+func NewLimitedProvider(name string, provider entity.GeoProvider, cfg LimitConfig) (*LimitedProvider, error) {
+	if cfg.Policy == "" {
+		cfg.Policy = LimitQueue
+	}
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = 5 * time.Second
+	}
+	l := &LimitedProvider{name: name, provider: provider, cfg: cfg}
+	if cfg.Rate > 0 {
+		l.bucket = adapter.NewTokenBucket(cfg.Rate, cfg.Burst)
+	}
+	if cfg.DailyQuota > 0 {
+		quota, err := adapter.NewDailyQuota(cfg.DailyQuota, cfg.QuotaFile, cfg.QuotaLocation)
+		if err != nil {
+			return nil, fmt.Errorf("%s: load quota: %w", name, err)
+		}
+		l.quota = quota
+	}
+	return l, nil
+}
+
+func (l *LimitedProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	if err := l.acquire(ctx); err != nil {
+		return entity.ResponseAddresses{}, err
+	}
+	return l.provider.GetGeoCoordinatesAddress(withAttemptGate(ctx, l.acquire), query)
+}
+
+func (l *LimitedProvider) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	if err := l.acquire(ctx); err != nil {
+		return entity.ResponseAddresses{}, err
+	}
+	return l.provider.GetGeoCoordinatesGeocode(withAttemptGate(ctx, l.acquire), lat, lng)
+}
+
+func (l *LimitedProvider) AddressSearch(ctx context.Context, input string) ([]*entity.Address, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return l.provider.AddressSearch(withAttemptGate(ctx, l.acquire), input)
+}
+
+func (l *LimitedProvider) GeoCode(ctx context.Context, lat, lng string) ([]*entity.Address, error) {
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return l.provider.GeoCode(withAttemptGate(ctx, l.acquire), lat, lng)
+}
+
+func (l *LimitedProvider) Quotas() []entity.QuotaStatus {
+	status := entity.QuotaStatus{Provider: l.name, Policy: string(l.cfg.Policy), Rate: l.cfg.Rate, Burst: l.cfg.Burst}
+	if l.quota != nil {
+		limit, used, resetAt := l.quota.Status()
+		status.Daily = &entity.DailyQuotaStatus{Limit: limit, Used: used, Remaining: limit - used, ResetAt: resetAt}
+	}
+	return []entity.QuotaStatus{status}
+}
+
+// acquire проверяет квоту, получает токен по политике и списывает вызов из квоты.
+// При LimitQueue ожидание ограничено и MaxWait, и ctx.
+func (l *LimitedProvider) acquire(ctx context.Context) error {
+	if l.quota != nil && l.quota.Remaining() <= 0 {
+		return l.reject("quota", entity.ErrQuotaExhausted)
+	}
+	if l.bucket != nil {
+		if l.cfg.Policy == LimitQueue {
+			waitCtx, cancel := context.WithTimeout(ctx, l.cfg.MaxWait)
+			err := l.bucket.Wait(waitCtx)
+			cancel()
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return l.reject("rate", entity.ErrRateLimited)
+			}
+		} else if !l.bucket.Allow() {
+			return l.reject("rate", entity.ErrRateLimited)
+		}
+	}
+	if l.quota != nil && !l.quota.Take() {
+		return l.reject("quota", entity.ErrQuotaExhausted)
+	}
+	return nil
+}
+
+// Close сохраняет счётчик квоты
+func (l *LimitedProvider) Close() error {
+	if l.quota == nil {
+		return nil
+	}
+	return l.quota.Close()
+}
+
+func (l *LimitedProvider) reject(reason string, err error) error {
+	adapter.DefaultMetrics.Inc("limit." + l.name + "." + reason + "_rejected")
+	if l.cfg.Policy == LimitStaleOnly {
+		return fmt.Errorf("%s: %w: %w", l.name, entity.ErrStaleOnly, err)
+	}
+	return fmt.Errorf("%s: %w", l.name, err)
+}
+
+// limitError сообщает, что запрос отклонён клиентскими лимитами и до провайдера не дошёл
+func limitError(err error) bool {
+	return errors.Is(err, entity.ErrRateLimited) || errors.Is(err, entity.ErrQuotaExhausted)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestLimitedProviderPolicies(t *testing.T) {
+	answer := entity.ResponseAddresses{Addresses: []*entity.Address{{City: "Москва"}}}
+
+	tests := []struct {
+		name         string
+		policy       LimitPolicy
+		wantProvider string
+		wantErr      error
+	}{
+		{name: "fail fast falls through", policy: LimitFailFast, wantProvider: "nominatim"},
+		{name: "stale only does not fall through", policy: LimitStaleOnly, wantErr: entity.ErrStaleOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubProvider{geo: answer}
+			limited, err := NewLimitedProvider("dadata", primary, LimitConfig{DailyQuota: 1, Policy: tt.policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			secondary := &stubProvider{geo: answer}
+			f := NewFailoverProvider(NamedProvider{Name: "dadata", Provider: limited}, NamedProvider{Name: "nominatim", Provider: secondary})
+
+			if geo, err := f.GetGeoCoordinatesAddress(context.Background(), "query"); err != nil || geo.Provider != "dadata" {
+				t.Fatalf("expected first call to reach dadata, got %q %v", geo.Provider, err)
+			}
+			geo, err := f.GetGeoCoordinatesAddress(context.Background(), "query")
+			if primary.calls != 1 {
+				t.Errorf("expected quota to stop the second call, dadata called %d times", primary.calls)
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, entity.ErrQuotaExhausted) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if geo.Provider != tt.wantProvider {
+				t.Errorf("expected provider %s, got %s", tt.wantProvider, geo.Provider)
+			}
+		})
+	}
+}
+
+func TestLimitedProviderLimitsRetries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	repo := newTestGeoRepo(t, "key", "secret", WithBaseURL(srv.URL), WithHTTPClient(srv.Client()),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}))
+	quotaFile := filepath.Join(t.TempDir(), "quota.json")
+	limited, err := NewLimitedProvider("dadata", repo, LimitConfig{DailyQuota: 2, QuotaFile: quotaFile, Policy: LimitFailFast})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = limited.GetGeoCoordinatesAddress(context.Background(), "query")
+	var upstream *entity.UpstreamError
+	if !errors.As(err, &upstream) || upstream.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected last upstream error, got %v", err)
+	}
+	// Вторая попытка съела остаток квоты, третья до DaData не дошла
+	if calls != 2 {
+		t.Errorf("expected retries to stop at the quota, got %d calls", calls)
+	}
+	if err := limited.Close(); err != nil {
+		t.Fatal(err)
+	}
+	restarted, err := NewLimitedProvider("dadata", repo, LimitConfig{DailyQuota: 2, QuotaFile: quotaFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+	if daily := restarted.Quotas()[0].Daily; daily.Used != 2 {
+		t.Errorf("expected retries to be saved in the quota on close, got %+v", daily)
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+)
+
+// chainFromEnv повторяет разбор GEO_PROVIDER в cmd
+func chainFromEnv(t *testing.T, configFor func(name string) ProviderConfig) (*FailoverProvider, error) {
+	t.Helper()
+	return NewProviderChain(strings.Split(os.Getenv("GEO_PROVIDER"), ","), configFor)
+}
+
+func TestProviderChainFromEnv(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "addresses.csv")
+	if err := os.WriteFile(dataFile, []byte("lat;lon;city;street;house\n55.7539;37.6208;Москва;Красная площадь;1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GEO_PROVIDER", "nominatim, offline,")
+	f, err := chainFromEnv(t, func(name string) ProviderConfig {
+		cfg := ProviderConfig{DataFile: dataFile}
+		if name == "nominatim" {
+			cfg.Breaker = adapter.BreakerConfig{FailureThreshold: 3}
+		}
+		return cfg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.providers) != 2 || f.providers[0].Name != "nominatim" || f.providers[1].Name != "offline" {
+		t.Fatalf("unexpected chain %+v", f.providers)
+	}
+	breaker, ok := f.providers[0].Provider.(*BreakerProvider)
+	if !ok {
+		t.Fatalf("expected nominatim behind a breaker, got %T", f.providers[0].Provider)
+	}
+	if _, ok := breaker.provider.(*NominatimRepo); !ok {
+		t.Errorf("expected NominatimRepo, got %T", breaker.provider)
+	}
+	if _, ok := f.providers[1].Provider.(*OfflineRepo); !ok {
+		t.Errorf("expected bare OfflineRepo, got %T", f.providers[1].Provider)
+	}
+}
+
+func TestProviderChainErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		cfg     ProviderConfig
+		wantErr string
+	}{
+		{name: "unknown provider", env: "nominatim,yandex", wantErr: `unknown geo provider "yandex", available: [dadata nominatim offline]`},
+		{name: "missing api key", env: "dadata", wantErr: "dadata: api key is required"},
+		{name: "invalid base url", env: "dadata", cfg: ProviderConfig{APIKey: "key", BaseURL: "dadata.local/api"},
+			wantErr: `dadata: invalid base url "dadata.local/api/": scheme and host are required`},
+		{name: "missing data file", env: "offline", wantErr: "offline: data file is required"},
+		{name: "empty", env: " , ", wantErr: "no geo providers configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GEO_PROVIDER", tt.env)
+			_, err := chainFromEnv(t, func(name string) ProviderConfig {
+				return tt.cfg
+			})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
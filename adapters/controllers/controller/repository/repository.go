@@ -2,6 +2,7 @@ package repository
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
@@ -20,7 +21,10 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User already exists", http.StatusConflict)
 		return
 	}
-	if err := usecase.Register(&user); err != nil {
+	if err := usecase.Register(&user); errors.Is(err, usecase.ErrReservedUsername) {
+		http.Error(w, "User already exists", http.StatusConflict)
+		return
+	} else if err != nil {
 		log.Printf("Error registering user: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
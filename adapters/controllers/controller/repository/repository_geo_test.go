@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"testing"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// Перезаписать фикстуры настоящими ответами DaData:
+//
+//	DADATA_API_KEY=... go test ./adapters/controllers/controller/repository -run TestGeoRepo -record
+var recordFixtures = flag.Bool("record", false, "записать фикстуры DaData в testdata/dadata")
+
+const dadataFixtures = "testdata/dadata"
+
+// newFixtureGeoRepo создаёт GeoRepo, который ходит в фикстуры, а с -record - в настоящий DaData
+func newFixtureGeoRepo(t *testing.T) *GeoRepo {
+	t.Helper()
+	if *recordFixtures {
+		apiKey := os.Getenv("DADATA_API_KEY")
+		if apiKey == "" {
+			t.Fatal("DADATA_API_KEY is required to record fixtures")
+		}
+		transport := &adapter.RecordingTransport{Dir: dadataFixtures}
+		return newTestGeoRepo(t, apiKey, os.Getenv("DADATA_SECRET_KEY"), WithHTTPClient(&http.Client{Transport: transport}))
+	}
+
+	transport, err := adapter.NewReplayTransport(dadataFixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, miss := range transport.Misses() {
+			t.Logf("no fixture for %s", miss)
+		}
+	})
+	return newTestGeoRepo(t, "test-key", "test-secret", WithHTTPClient(&http.Client{Transport: transport}))
+}
+
+func newTestGeoRepo(t *testing.T, apiKey, secretKey string, opts ...GeoRepoOption) *GeoRepo {
+	t.Helper()
+	repo, err := NewGeoService(apiKey, secretKey, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestGeoRepoAddress(t *testing.T) {
+	repo := newFixtureGeoRepo(t)
+	ctx := context.Background()
+
+	geo, err := repo.GetGeoCoordinatesAddress(ctx, "москва тверская 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geo.Addresses) != 2 || geo.Addresses[0].Street != "Тверская" || geo.Addresses[0].Lat != 55.7573 {
+		t.Errorf("unexpected addresses %+v", geo.Addresses)
+	}
+	if details := geo.Addresses[0].Details; details == nil || details.PostalCode != "125009" || details.FiasID == "" {
+		t.Errorf("expected full DaData address in details, got %+v", details)
+	}
+
+	// AddressSearch ходит в тот же метод через клиент ekomobile
+	addresses, err := repo.AddressSearch(ctx, "москва тверская 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 || addresses[1].House != "10" {
+		t.Errorf("unexpected addresses %+v", addresses)
+	}
+
+	geo, err = repo.GetGeoCoordinatesAddress(ctx, "нет такого адреса")
+	if err != nil || len(geo.Addresses) != 0 {
+		t.Errorf("expected empty result, got %+v, %v", geo.Addresses, err)
+	}
+}
+
+func TestGeoRepoGeocode(t *testing.T) {
+	repo := newFixtureGeoRepo(t)
+	ctx := context.Background()
+
+	geo, err := repo.GetGeoCoordinatesGeocode(ctx, 55.7522, 37.6156)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geo.Addresses) != 1 || geo.Addresses[0].Street != "Красная" || geo.Addresses[0].House != "1" {
+		t.Errorf("unexpected addresses %+v", geo.Addresses)
+	}
+
+	// GeoCode собирает тело вручную, с другими пробелами, но попадает в ту же фикстуру
+	addresses, err := repo.GeoCode(ctx, "55.7522", "37.6156")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 {
+		t.Errorf("expected 1 address, got %d", len(addresses))
+	}
+}
+
+func TestGeoRepoUpstreamError(t *testing.T) {
+	if *recordFixtures {
+		t.Skip("ответ с ошибкой квоты не воспроизвести по запросу")
+	}
+	repo := newFixtureGeoRepo(t)
+
+	_, err := repo.GetGeoCoordinatesGeocode(context.Background(), 0, 0)
+	var upstream *entity.UpstreamError
+	if !errors.As(err, &upstream) || !upstream.Quota() {
+		t.Fatalf("expected quota upstream error, got %v", err)
+	}
+}
+
+func TestGeoRepoMissingFixture(t *testing.T) {
+	if *recordFixtures {
+		t.Skip()
+	}
+	transport, err := adapter.NewReplayTransport(dadataFixtures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newTestGeoRepo(t, "test-key", "test-secret", WithHTTPClient(&http.Client{Transport: transport}))
+
+	_, err = repo.GetGeoCoordinatesAddress(context.Background(), "незаписанный запрос")
+	if !errors.Is(err, adapter.ErrNoFixture) {
+		t.Fatalf("expected ErrNoFixture, got %v", err)
+	}
+	if len(transport.Misses()) != 1 {
+		t.Errorf("expected one miss, got %v", transport.Misses())
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// nominatimBaseURL - публичный сервер Nominatim (OpenStreetMap)
+const nominatimBaseURL = "https://nominatim.openstreetmap.org/"
+
+// NominatimRepo - геопровайдер поверх Nominatim-совместимого HTTP API
+type NominatimRepo struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+}
+
+func NewNominatimService(baseURL, userAgent string) *NominatimRepo {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	if userAgent == "" {
+		userAgent = "go-kata-geo/1.0"
+	}
+	return &NominatimRepo{baseURL: baseURL, userAgent: userAgent, client: http.DefaultClient}
+}
+
+type nominatimPlace struct {
+	Lat     string `json:"lat"`
+	Lon     string `json:"lon"`
+	Address struct {
+		City        string `json:"city"`
+		Town        string `json:"town"`
+		Village     string `json:"village"`
+		Hamlet      string `json:"hamlet"`
+		Road        string `json:"road"`
+		Pedestrian  string `json:"pedestrian"`
+		HouseNumber string `json:"house_number"`
+		Suburb      string `json:"suburb"`
+		State       string `json:"state"`
+		Postcode    string `json:"postcode"`
+		Country     string `json:"country"`
+		CountryCode string `json:"country_code"`
+	} `json:"address"`
+	Error string `json:"error"`
+}
+
+func (p nominatimPlace) toAddress() *entity.Address {
+	address := &entity.Address{
+		City:   firstNonEmpty(p.Address.City, p.Address.Town, p.Address.Village, p.Address.Hamlet),
+		Street: firstNonEmpty(p.Address.Road, p.Address.Pedestrian),
+		House:  p.Address.HouseNumber,
+	}
+	setProviderCoordinates("nominatim", address, p.Lat, p.Lon)
+	// В Nominatim нет идентификаторов ФИАС/КЛАДР и кодов качества, заполняем что есть
+	address.Details = &entity.Data{
+		PostalCode:     p.Address.Postcode,
+		Country:        entity.Country(p.Address.Country),
+		CountryISOCode: entity.CountryISOCode(strings.ToUpper(p.Address.CountryCode)),
+		Region:         entity.City(p.Address.State),
+		City:           entity.City(address.City),
+		CityDistrict:   p.Address.Suburb,
+		Street:         entity.Street(address.Street),
+		House:          address.House,
+		GeoLat:         p.Lat,
+		GeoLon:         p.Lon,
+	}
+	return address
+}
+
+func (n *NominatimRepo) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	params := url.Values{"q": {query}, "format": {"jsonv2"}, "addressdetails": {"1"}, "limit": {"10"}}
+	var places []nominatimPlace
+	if err := n.get(ctx, "search", params, &places); err != nil {
+		return entity.ResponseAddresses{}, err
+	}
+	var addresses entity.ResponseAddresses
+	for _, place := range places {
+		addresses.Addresses = append(addresses.Addresses, place.toAddress())
+	}
+	return addresses, nil
+}
+
+func (n *NominatimRepo) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	params := url.Values{
+		"lat":            {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon":            {strconv.FormatFloat(lng, 'f', -1, 64)},
+		"format":         {"jsonv2"},
+		"addressdetails": {"1"},
+	}
+	var place nominatimPlace
+	if err := n.get(ctx, "reverse", params, &place); err != nil {
+		return entity.ResponseAddresses{}, err
+	}
+	// Nominatim отвечает {"error": "Unable to geocode"}, если рядом ничего нет
+	if place.Error != "" {
+		return entity.ResponseAddresses{}, nil
+	}
+	return entity.ResponseAddresses{Addresses: []*entity.Address{place.toAddress()}}, nil
+}
+
+func (n *NominatimRepo) AddressSearch(ctx context.Context, input string) ([]*entity.Address, error) {
+	geo, err := n.GetGeoCoordinatesAddress(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []*entity.Address
+	for _, address := range geo.Addresses {
+		if address.City == "" || address.Street == "" {
+			continue
+		}
+		res = append(res, address)
+	}
+	return res, nil
+}
+
+func (n *NominatimRepo) GeoCode(ctx context.Context, lat, lng string) ([]*entity.Address, error) {
+	latValue, lngValue, err := parsePoint(lat, lng)
+	if err != nil {
+		return nil, err
+	}
+	geo, err := n.GetGeoCoordinatesGeocode(ctx, float64(latValue), float64(lngValue))
+	if err != nil {
+		return nil, err
+	}
+	return geo.Addresses, nil
+}
+
+func (n *NominatimRepo) get(ctx context.Context, path string, params url.Values, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", n.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", n.userAgent)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return entity.NewUpstreamError("nominatim", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+const nominatimSearchBody = `[
+	{"lat": "55.7573", "lon": "37.6134", "address": {"city": "Москва", "road": "Тверская улица", "house_number": "1",
+		"suburb": "Тверской район", "state": "Москва", "postcode": "125009", "country": "Россия", "country_code": "ru"}},
+	{"lat": "55.6", "lon": "37.5", "address": {"village": "Коммунарка", "pedestrian": "Бульвар", "country_code": "ru"}},
+	{"lat": "55.5", "lon": "37.4", "address": {"state": "Московская область"}}
+]`
+
+func newTestNominatim(t *testing.T, handler http.HandlerFunc) *NominatimRepo {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	// Без завершающего "/": NewNominatimService добавляет его сам
+	return NewNominatimService(srv.URL, "")
+}
+
+func TestNominatimSearch(t *testing.T) {
+	repo := newTestNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/search" || q.Get("q") != "москва тверская 1" || q.Get("format") != "jsonv2" || q.Get("addressdetails") != "1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if r.Header.Get("User-Agent") != "go-kata-geo/1.0" {
+			t.Errorf("expected default user agent, got %q", r.Header.Get("User-Agent"))
+		}
+		w.Write([]byte(nominatimSearchBody))
+	})
+	ctx := context.Background()
+
+	geo, err := repo.GetGeoCoordinatesAddress(ctx, "москва тверская 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geo.Addresses) != 3 {
+		t.Fatalf("expected 3 addresses, got %+v", geo.Addresses)
+	}
+	first := geo.Addresses[0]
+	if first.City != "Москва" || first.Street != "Тверская улица" || first.House != "1" || first.Lat != 55.7573 || first.Lon != 37.6134 {
+		t.Errorf("unexpected address %+v", first)
+	}
+	if d := first.Details; d == nil || d.PostalCode != "125009" || d.CountryISOCode != "RU" || d.CityDistrict != "Тверской район" || d.GeoLat != "55.7573" {
+		t.Errorf("unexpected details %+v", first.Details)
+	}
+	// Населённый пункт и улица берутся из запасных полей
+	if second := geo.Addresses[1]; second.City != "Коммунарка" || second.Street != "Бульвар" {
+		t.Errorf("unexpected fallback address %+v", second)
+	}
+
+	// AddressSearch отбрасывает места без города или улицы
+	addresses, err := repo.AddressSearch(ctx, "москва тверская 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 {
+		t.Errorf("expected 2 addresses, got %+v", addresses)
+	}
+}
+
+func TestNominatimInvalidCoordinates(t *testing.T) {
+	repo := newTestNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"lat": "955.1", "lon": "37.6", "address": {"city": "Москва", "road": "Тверская улица"}},
+			{"lat": "", "lon": "", "address": {"city": "Москва", "road": "Моховая улица"}}]`))
+	})
+	invalid := adapter.DefaultMetrics.Get("provider.nominatim.invalid_coordinates")
+
+	geo, err := repo.GetGeoCoordinatesAddress(context.Background(), "москва")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Адрес с невозможными координатами отдаётся без них, а не как точка 0,0
+	for _, address := range geo.Addresses {
+		if address.HasCoordinates {
+			t.Errorf("expected address without coordinates, got %+v", address)
+		}
+	}
+	if got := adapter.DefaultMetrics.Get("provider.nominatim.invalid_coordinates") - invalid; got != 1 {
+		t.Errorf("expected 1 invalid coordinate pair, got %d", got)
+	}
+}
+
+func TestNominatimReverse(t *testing.T) {
+	repo := newTestNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/reverse" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if q.Get("lat") == "0" {
+			w.Write([]byte(`{"error": "Unable to geocode"}`))
+			return
+		}
+		if q.Get("lat") != "55.7522" || q.Get("lon") != "37.6156" {
+			t.Errorf("unexpected point %s,%s", q.Get("lat"), q.Get("lon"))
+		}
+		w.Write([]byte(`{"lat": "55.7522", "lon": "37.6156", "address": {"town": "Москва", "road": "Моховая улица", "house_number": "11"}}`))
+	})
+	ctx := context.Background()
+
+	addresses, err := repo.GeoCode(ctx, "55.7522", "37.6156")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || addresses[0].City != "Москва" || addresses[0].House != "11" {
+		t.Errorf("unexpected addresses %+v", addresses)
+	}
+
+	// "Unable to geocode" - пустой ответ, а не ошибка
+	geo, err := repo.GetGeoCoordinatesGeocode(ctx, 0, 0)
+	if err != nil || len(geo.Addresses) != 0 {
+		t.Errorf("expected empty result, got %+v, %v", geo.Addresses, err)
+	}
+
+	if _, err := repo.GeoCode(ctx, "north", "37.6156"); err == nil {
+		t.Error("expected invalid coordinates error")
+	}
+}
+
+func TestNominatimErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantQuota  bool
+	}{
+		{name: "rate limited", status: http.StatusTooManyRequests, body: "Too Many Requests", wantStatus: 429, wantQuota: true},
+		{name: "blocked", status: http.StatusForbidden, body: "Access blocked", wantStatus: 403, wantQuota: true},
+		{name: "bad request", status: http.StatusBadRequest, body: "Nothing to search for", wantStatus: 400},
+		{name: "server error", status: http.StatusBadGateway, body: "Bad Gateway", wantStatus: 502},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, tt.body, tt.status)
+			})
+
+			_, err := repo.GetGeoCoordinatesAddress(context.Background(), "москва")
+			var upstream *entity.UpstreamError
+			if !errors.As(err, &upstream) {
+				t.Fatalf("expected upstream error, got %v", err)
+			}
+			if upstream.Provider != "nominatim" || upstream.StatusCode != tt.wantStatus || upstream.Quota() != tt.wantQuota {
+				t.Errorf("unexpected upstream error %+v", upstream)
+			}
+		})
+	}
+
+	t.Run("malformed body", func(t *testing.T) {
+		repo := newTestNominatim(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"not": "a list"`))
+		})
+		_, err := repo.GetGeoCoordinatesAddress(context.Background(), "москва")
+		var upstream *entity.UpstreamError
+		if err == nil || errors.As(err, &upstream) {
+			t.Errorf("expected decode error, got %v", err)
+		}
+	})
+}
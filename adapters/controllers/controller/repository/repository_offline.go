@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// OfflineOption настраивает OfflineRepo
+type OfflineOption func(*OfflineRepo)
+
+// WithOfflineNearest задаёт, сколько ближайших адресов отдавать
+func WithOfflineNearest(n int) OfflineOption {
+	return func(o *OfflineRepo) {
+		o.nearest = n
+	}
+}
+
+// WithOfflineRadius задаёт радиус поиска в метрах
+func WithOfflineRadius(meters float64) OfflineOption {
+	return func(o *OfflineRepo) {
+		o.radius = meters
+	}
+}
+
+// WithOfflineSearchLimit задаёт, сколько адресов отдавать при поиске по строке
+func WithOfflineSearchLimit(n int) OfflineOption {
+	return func(o *OfflineRepo) {
+		o.searchLimit = n
+	}
+}
+
+// OfflineRepo - геопровайдер без сети: адреса с координатами загружаются из файла
+// в k-d дерево, обратное геокодирование отдаёт ближайшие адреса в пределах радиуса.
+// Поиск по строке идёт по полнотекстовому индексу тех же адресов.
+type OfflineRepo struct {
+	tree        *kdNode
+	index       *searchIndex
+	size        int
+	nearest     int
+	radius      float64
+	searchLimit int
+}
+
+var _ entity.GeoProvider = (*OfflineRepo)(nil)
+
+// NewOfflineService загружает адреса из CSV (.csv) или GeoJSON (.geojson, .json).
+// В CSV нужна строка заголовков с колонками lat и lon (или geo_lat/geo_lon, lng,
+// latitude/longitude), city, street и house. В GeoJSON берутся точки (Point),
+// поля адреса - из properties city/street/house или addr:city/addr:street/addr:housenumber.
+func NewOfflineService(path string, opts ...OfflineOption) (*OfflineRepo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var points []kdPoint
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		points, err = loadOfflineCSV(f)
+	case ".geojson", ".json":
+		points, err = loadOfflineGeoJSON(f)
+	default:
+		return nil, fmt.Errorf("offline: unsupported file type %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("offline: load %s: %w", path, err)
+	}
+	return newOfflineRepo(points, opts...), nil
+}
+
+func newOfflineRepo(points []kdPoint, opts ...OfflineOption) *OfflineRepo {
+	o := &OfflineRepo{size: len(points), nearest: 5, radius: 500, searchLimit: 10}
+	for _, opt := range opts {
+		opt(o)
+	}
+	addresses := make([]*entity.Address, len(points))
+	for i, p := range points {
+		addresses[i] = p.address
+	}
+	o.index = newSearchIndex(addresses)
+	o.tree = buildKDTree(points, 0)
+	return o
+}
+
+// Len возвращает количество загруженных адресов
+func (o *OfflineRepo) Len() int {
+	return o.size
+}
+
+func (o *OfflineRepo) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	var geo entity.ResponseAddresses
+	for _, match := range o.tree.nearest(toUnitSphere(lat, lng), o.nearest, chordForDistance(o.radius)) {
+		geo.Addresses = append(geo.Addresses, match.point.address)
+	}
+	return geo, nil
+}
+
+func (o *OfflineRepo) GeoCode(ctx context.Context, lat, lng string) ([]*entity.Address, error) {
+	latValue, lngValue, err := parsePoint(lat, lng)
+	if err != nil {
+		return nil, err
+	}
+	geo, err := o.GetGeoCoordinatesGeocode(ctx, float64(latValue), float64(lngValue))
+	return geo.Addresses, err
+}
+
+func (o *OfflineRepo) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	return entity.ResponseAddresses{Addresses: o.index.search(query, o.searchLimit)}, nil
+}
+
+func (o *OfflineRepo) AddressSearch(ctx context.Context, input string) ([]*entity.Address, error) {
+	return o.index.search(input, o.searchLimit), nil
+}
+
+func loadOfflineCSV(r io.Reader) ([]kdPoint, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	// Выгрузки из Excel часто разделены точкой с запятой
+	if len(header) == 1 && strings.Contains(header[0], ";") {
+		header = strings.Split(header[0], ";")
+		reader.Comma = ';'
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	latCol := column("lat", "geo_lat", "latitude")
+	lonCol := column("lon", "lng", "geo_lon", "longitude")
+	if latCol < 0 || lonCol < 0 {
+		return nil, errors.New("csv: lat and lon columns are required")
+	}
+	cityCol, streetCol, houseCol := column("city"), column("street"), column("house")
+
+	var points []kdPoint
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		lat, err := strconv.ParseFloat(field(latCol), 64)
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: invalid lat %q", line, field(latCol))
+		}
+		lon, err := strconv.ParseFloat(field(lonCol), 64)
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: invalid lon %q", line, field(lonCol))
+		}
+		if err := entity.ValidatePoint(entity.Coordinate(lat), entity.Coordinate(lon), "lat", "lon"); err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		points = append(points, newKDPoint(lat, lon, &entity.Address{
+			City:           field(cityCol),
+			Street:         field(streetCol),
+			House:          field(houseCol),
+			Lat:            entity.Coordinate(lat),
+			Lon:            entity.Coordinate(lon),
+			HasCoordinates: true,
+		}))
+	}
+}
+
+type geoJSONCollection struct {
+	Features []struct {
+		Geometry struct {
+			Type string `json:"type"`
+			// У линий и полигонов координаты вложенные, поэтому разбираются только для точек
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	} `json:"features"`
+}
+
+func loadOfflineGeoJSON(r io.Reader) ([]kdPoint, error) {
+	var collection geoJSONCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, err
+	}
+	var points []kdPoint
+	for _, feature := range collection.Features {
+		// Линии и полигоны пропускаем: для обратного геокодирования нужны точки
+		if feature.Geometry.Type != "Point" {
+			continue
+		}
+		var coordinates []float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &coordinates); err != nil || len(coordinates) < 2 {
+			return nil, fmt.Errorf("geojson: invalid point coordinates %s", feature.Geometry.Coordinates)
+		}
+		// В GeoJSON порядок координат - долгота, широта
+		lon, lat := coordinates[0], coordinates[1]
+		if err := entity.ValidatePoint(entity.Coordinate(lat), entity.Coordinate(lon), "lat", "lon"); err != nil {
+			return nil, fmt.Errorf("geojson: %w", err)
+		}
+		prop := func(names ...string) string {
+			for _, name := range names {
+				switch v := feature.Properties[name].(type) {
+				case string:
+					if v != "" {
+						return v
+					}
+				case float64:
+					return strconv.FormatFloat(v, 'f', -1, 64)
+				}
+			}
+			return ""
+		}
+		points = append(points, newKDPoint(lat, lon, &entity.Address{
+			City:           prop("city", "addr:city"),
+			Street:         prop("street", "addr:street"),
+			House:          prop("house", "addr:housenumber"),
+			Lat:            entity.Coordinate(lat),
+			Lon:            entity.Coordinate(lon),
+			HasCoordinates: true,
+		}))
+	}
+	return points, nil
+}
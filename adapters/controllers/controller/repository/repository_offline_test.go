@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestOfflineServiceCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addresses.csv")
+	data := "lat;lon;city;street;house\n" +
+		"55.7539;37.6208;Москва;Красная площадь;1\n" +
+		"55.7558;37.6176;Москва;Тверская;1\n" +
+		"59.9398;30.3146;Санкт-Петербург;Дворцовая площадь;2\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewOfflineService(path, WithOfflineRadius(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Len() != 3 {
+		t.Fatalf("expected 3 addresses, got %d", repo.Len())
+	}
+
+	geo, err := repo.GetGeoCoordinatesGeocode(context.Background(), 55.7540, 37.6205)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(geo.Addresses) != 2 {
+		t.Fatalf("expected 2 addresses within radius, got %d", len(geo.Addresses))
+	}
+	if geo.Addresses[0].Street != "Красная площадь" {
+		t.Errorf("expected nearest address first, got %+v", geo.Addresses[0])
+	}
+
+	geo, _ = repo.GetGeoCoordinatesGeocode(context.Background(), 0, 0)
+	if len(geo.Addresses) != 0 {
+		t.Errorf("expected nothing far from data, got %d addresses", len(geo.Addresses))
+	}
+}
+
+func TestOfflineServiceGeoJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addresses.geojson")
+	data := `{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [37.6208, 55.7539]},
+		 "properties": {"addr:city": "Москва", "addr:street": "Красная площадь", "addr:housenumber": "1"}},
+		{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[37.6, 55.7], [37.7, 55.8]]}}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewOfflineService(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := repo.GeoCode(context.Background(), "55.7539", "37.6208")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || addresses[0].House != "1" || addresses[0].Lat != 55.7539 {
+		t.Errorf("unexpected addresses %+v", addresses)
+	}
+}
+
+// TestKDTreeMatchesBruteForce сверяет поиск по дереву с полным перебором
+func TestKDTreeMatchesBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	points := make([]kdPoint, 2000)
+	for i := range points {
+		lat, lng := 55+rnd.Float64(), 37+rnd.Float64()
+		points[i] = newKDPoint(lat, lng, &entity.Address{})
+	}
+	all := append([]kdPoint(nil), points...)
+	tree := buildKDTree(points, 0)
+
+	for i := 0; i < 50; i++ {
+		target := toUnitSphere(55+rnd.Float64(), 37+rnd.Float64())
+		maxChord := chordForDistance(3000)
+
+		var want []float64
+		for _, p := range all {
+			if d := chordDist(target, p.xyz); d <= maxChord {
+				want = append(want, d)
+			}
+		}
+		sort.Float64s(want)
+		if len(want) > 5 {
+			want = want[:5]
+		}
+
+		got := tree.nearest(target, 5, maxChord)
+		if len(got) != len(want) {
+			t.Fatalf("expected %d matches, got %d", len(want), len(got))
+		}
+		for j := range got {
+			if math.Abs(math.Sqrt(got[j].dist2)-want[j]) > 1e-12 {
+				t.Fatalf("match %d: expected distance %v, got %v", j, want[j], math.Sqrt(got[j].dist2))
+			}
+		}
+	}
+}
+
+func chordDist(a, b [3]float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return math.Sqrt(sum)
+}
@@ -25,14 +25,20 @@ func (r *Respond) OutputJSON(w http.ResponseWriter, responseData interface{}) {
 	}
 }
 
+// ErrorBadRequest кладёт *entity.FieldError в Data, чтобы клиент мог подсветить поле
 func (r *Respond) ErrorBadRequest(w http.ResponseWriter, err error) {
 	r.log.Info("http response bad request status code", zap.Error(err))
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
 	w.WriteHeader(http.StatusBadRequest)
+	var data interface{}
+	var fieldErr *entity.FieldError
+	if errors.As(err, &fieldErr) {
+		data = fieldErr
+	}
 	if err := json.NewEncoder(w).Encode(entity.Response{
 		Success: false,
 		Message: err.Error(),
-		Data:    nil,
+		Data:    data,
 	}); err != nil {
 		r.log.Info("response writer error on write", zap.Error(err))
 	}
@@ -51,6 +57,19 @@ func (r *Respond) ErrorForbidden(w http.ResponseWriter, err error) {
 	}
 }
 
+func (r *Respond) ErrorNotFound(w http.ResponseWriter, err error) {
+	r.log.Info("http response not found", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	if err := json.NewEncoder(w).Encode(entity.Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
+
 func (r *Respond) ErrorUnauthorized(w http.ResponseWriter, err error) {
 	r.log.Warn("http resposne Unauthorized", zap.Error(err))
 	w.Header().Set("Content-Type", "application/json;charset=utf-8")
@@ -79,3 +98,68 @@ func (r *Respond) ErrorInternal(w http.ResponseWriter, err error) {
 		r.log.Error("response writer error on write", zap.Error(err))
 	}
 }
+
+func (r *Respond) ErrorServiceUnavailable(w http.ResponseWriter, err error) {
+	r.log.Warn("http response service unavailable", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := json.NewEncoder(w).Encode(entity.Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
+
+func (r *Respond) ErrorGatewayTimeout(w http.ResponseWriter, err error) {
+	r.log.Warn("http response gateway timeout", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	if err := json.NewEncoder(w).Encode(entity.Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
+
+func (r *Respond) ErrorConflict(w http.ResponseWriter, err error) {
+	r.log.Info("http response conflict", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusConflict)
+	if err := json.NewEncoder(w).Encode(entity.Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
+
+func (r *Respond) ErrorBadGateway(w http.ResponseWriter, err error) {
+	r.log.Warn("http response bad gateway", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusBadGateway)
+	if err := json.NewEncoder(w).Encode(entity.Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
+
+func (r *Respond) ErrorTooManyRequests(w http.ResponseWriter, err error) {
+	r.log.Warn("http response too many requests", zap.Error(err))
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+	if err := json.NewEncoder(w).Encode(entity.Response{
+		Success: false,
+		Message: err.Error(),
+		Data:    nil,
+	}); err != nil {
+		r.log.Error("response writer error on write", zap.Error(err))
+	}
+}
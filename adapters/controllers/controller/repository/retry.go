@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+)
+
+// RetryPolicy - повторы запросов к провайдеру с экспоненциальной задержкой и jitter
+type RetryPolicy struct {
+	// MaxAttempts - всего попыток, включая первую; 1 и меньше отключает повторы
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type attemptGateKey struct{}
+
+// withAttemptGate передаёт в RetryPolicy.Do проверку, которую надо пройти перед каждым повтором.
+// Так LimitedProvider, стоящий над провайдером, ограничивает и повторы, а не только первый вызов.
+func withAttemptGate(ctx context.Context, gate func(ctx context.Context) error) context.Context {
+	return context.WithValue(ctx, attemptGateKey{}, gate)
+}
+
+func acquireAttempt(ctx context.Context) error {
+	if gate, ok := ctx.Value(attemptGateKey{}).(func(ctx context.Context) error); ok {
+		return gate(ctx)
+	}
+	return nil
+}
+
+// DefaultRetryPolicy - политика повторов DaData по умолчанию
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 200 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// Do выполняет запрос и повторяет его при сетевых ошибках, 429 и 5xx.
+// Неидемпотентные запросы и остальные 4xx не повторяются. Задержка берётся из Retry-After,
+// если сервер его прислал, иначе - случайная в пределах BaseDelay*2^n, но не больше MaxDelay.
+// Если следующая попытка не успевает до дедлайна контекста запроса или не проходит лимиты
+// (withAttemptGate), возвращается последний результат.
+func (p RetryPolicy) Do(client *http.Client, req *http.Request, idempotent bool, provider string) (*http.Response, error) {
+	attempts := p.MaxAttempts
+	if !idempotent || attempts < 1 {
+		attempts = 1
+	}
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := client.Do(req)
+		if attempt >= attempts || !retryable(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := p.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				// Раньше срока повторять нельзя, а ждать дольше MaxDelay не станем
+				if after > p.MaxDelay {
+					return resp, err
+				}
+				delay = after
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			adapter.DefaultMetrics.Inc("retry." + provider + ".deadline")
+			return resp, err
+		}
+
+		// Повтор - такой же вызов провайдера: он тратит токен и квоту, как и первый
+		if gateErr := acquireAttempt(ctx); gateErr != nil {
+			adapter.DefaultMetrics.Inc("retry." + provider + ".limited")
+			return resp, err
+		}
+
+		reason := retryReason(resp, err)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		adapter.DefaultMetrics.Inc("retry." + provider + ".attempts")
+		log.Printf("%s: retry %d/%d in %v after %s", provider, attempt, attempts-1, delay.Round(time.Millisecond), reason)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// full jitter: равномерно от 0 до delay
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		// Отмена или дедлайн самого запроса - не повод повторять
+		if ctx.Err() != nil {
+			return false
+		}
+		// http.Client оборачивает любую ошибку транспорта в *url.Error, который сам реализует
+		// net.Error, поэтому смотрим на исходную ошибку
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+// retryAfter разбирает Retry-After в секундах или в формате HTTP-даты
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
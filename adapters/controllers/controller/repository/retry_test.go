@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGeoRepoRetry(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		wantCalls  int32
+		wantStatus int
+	}{
+		{name: "5xx is retried", statuses: []int{502, 503, 200}, wantCalls: 3, wantStatus: 200},
+		{name: "429 is retried", statuses: []int{429, 200}, wantCalls: 2, wantStatus: 200},
+		{name: "4xx is not retried", statuses: []int{400, 200}, wantCalls: 1, wantStatus: 400},
+		{name: "attempts are capped", statuses: []int{500, 500, 500, 500}, wantCalls: 3, wantStatus: 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&calls, 1)
+				body := make([]byte, 64)
+				k, _ := r.Body.Read(body)
+				if !strings.Contains(string(body[:k]), "query") {
+					t.Errorf("attempt %d: request body was not resent", n)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+			req, _ := http.NewRequest("POST", srv.URL, strings.NewReader(`{"query": "x"}`))
+			resp, err := policy.Do(srv.Client(), req, true, "test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestRetryNotIdempotent(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL, nil)
+	resp, err := DefaultRetryPolicy.Do(srv.Client(), req, false, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if d, ok := retryAfter("2"); !ok || d != 2*time.Second {
+		t.Errorf("expected 2s, got %v", d)
+	}
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("expected invalid Retry-After to be ignored")
+	}
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d, ok := retryAfter(at); !ok || d <= 0 || d > time.Minute {
+		t.Errorf("expected about a minute, got %v", d)
+	}
+}
+
+func TestRetryNotNetworkError(t *testing.T) {
+	var calls int32
+	client := &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.New("broken transport")
+	})}
+
+	req, _ := http.NewRequest("POST", "http://dadata.invalid/", nil)
+	if _, err := DefaultRetryPolicy.Do(client, req, true, "test"); err == nil {
+		t.Fatal("expected error")
+	}
+	if calls != 1 {
+		t.Errorf("expected only network errors to be retried, got %d calls", calls)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// Веса совпадения слова запроса со словом адреса
+const (
+	scoreExact  = 1.0
+	scorePrefix = 0.8
+	scoreFuzzy  = 0.6
+)
+
+// searchStopWords - сокращения, которые пишут по-разному или опускают: "ул.", "д.", "г."
+var searchStopWords = map[string]struct{}{
+	"г": {}, "город": {}, "ул": {}, "улица": {}, "д": {}, "дом": {}, "пр": {}, "просп": {},
+	"проспект": {}, "пер": {}, "переулок": {}, "к": {}, "корп": {}, "корпус": {}, "стр": {},
+}
+
+// searchIndex - полнотекстовый индекс по адресам: словарь с префиксным поиском
+// и триграммы для поиска с опечатками
+type searchIndex struct {
+	docs []*entity.Address
+	// docTerms - количество слов в адресе, при равенстве очков короче - выше
+	docTerms []int
+	vocab    []string         // отсортированный словарь
+	postings map[string][]int // слово -> номера адресов
+	trigrams map[string][]int // триграмма -> номера слов в vocab
+}
+
+func newSearchIndex(addresses []*entity.Address) *searchIndex {
+	idx := &searchIndex{
+		docs:     addresses,
+		docTerms: make([]int, len(addresses)),
+		postings: make(map[string][]int),
+		trigrams: make(map[string][]int),
+	}
+	for i, address := range addresses {
+		terms := searchTerms(address.City + " " + address.Street + " " + address.House)
+		idx.docTerms[i] = len(terms)
+		seen := make(map[string]struct{}, len(terms))
+		for _, term := range terms {
+			if _, ok := seen[term]; ok {
+				continue
+			}
+			seen[term] = struct{}{}
+			idx.postings[term] = append(idx.postings[term], i)
+		}
+	}
+	idx.vocab = make([]string, 0, len(idx.postings))
+	for term := range idx.postings {
+		idx.vocab = append(idx.vocab, term)
+	}
+	sort.Strings(idx.vocab)
+	for i, term := range idx.vocab {
+		for _, tri := range termTrigrams(term) {
+			idx.trigrams[tri] = append(idx.trigrams[tri], i)
+		}
+	}
+	return idx
+}
+
+// search находит адреса, в которых нашлось каждое слово запроса: целиком, по префиксу
+// или с опечаткой. Результаты упорядочены по сумме очков совпадений.
+func (idx *searchIndex) search(query string, limit int) []*entity.Address {
+	terms := searchTerms(query)
+	if len(terms) == 0 || limit <= 0 {
+		return nil
+	}
+
+	var scores map[int]float64
+	for _, term := range terms {
+		termScores := make(map[int]float64)
+		for word, score := range idx.matchTerm(term) {
+			for _, doc := range idx.postings[word] {
+				if score > termScores[doc] {
+					termScores[doc] = score
+				}
+			}
+		}
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for doc, score := range scores {
+			if termScore, ok := termScores[doc]; ok {
+				scores[doc] = score + termScore
+			} else {
+				delete(scores, doc)
+			}
+		}
+	}
+
+	docs := make([]int, 0, len(scores))
+	for doc := range scores {
+		docs = append(docs, doc)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		if idx.docTerms[a] != idx.docTerms[b] {
+			return idx.docTerms[a] < idx.docTerms[b]
+		}
+		return a < b
+	})
+	if len(docs) > limit {
+		docs = docs[:limit]
+	}
+	res := make([]*entity.Address, len(docs))
+	for i, doc := range docs {
+		res[i] = idx.docs[doc]
+	}
+	return res
+}
+
+// matchTerm подбирает слова словаря для слова запроса и их очки
+func (idx *searchIndex) matchTerm(term string) map[string]float64 {
+	matches := make(map[string]float64)
+	if _, ok := idx.postings[term]; ok {
+		matches[term] = scoreExact
+	}
+	// Префиксы: "твер" находит "тверская"
+	for i := sort.SearchStrings(idx.vocab, term); i < len(idx.vocab) && strings.HasPrefix(idx.vocab[i], term); i++ {
+		if _, ok := matches[idx.vocab[i]]; !ok {
+			matches[idx.vocab[i]] = scorePrefix
+		}
+	}
+	// Номера домов с опечатками не ищем: "12" и "13" - разные дома
+	if isNumeric(term) || len([]rune(term)) < 4 {
+		return matches
+	}
+
+	// Кандидаты с опечаткой - слова хотя бы с одной общей триграммой: в коротком слове
+	// одна опечатка портит почти все триграммы. Лишних кандидатов отсеивает editDistance.
+	shared := make(map[int]struct{})
+	for _, tri := range termTrigrams(term) {
+		for _, i := range idx.trigrams[tri] {
+			shared[i] = struct{}{}
+		}
+	}
+	maxDistance := 1
+	if len([]rune(term)) >= 8 {
+		maxDistance = 2
+	}
+	for i := range shared {
+		word := idx.vocab[i]
+		if _, ok := matches[word]; ok {
+			continue
+		}
+		if distance := editDistance(term, word, maxDistance); distance <= maxDistance {
+			matches[word] = scoreFuzzy * (1 - float64(distance)/float64(len([]rune(word))+1))
+		}
+	}
+	return matches
+}
+
+// searchTerms приводит текст к словам индекса: нижний регистр, ё -> е, без знаков и сокращений
+func searchTerms(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := fields[:0]
+	for _, field := range fields {
+		if _, stop := searchStopWords[field]; !stop {
+			terms = append(terms, field)
+		}
+	}
+	return terms
+}
+
+func termTrigrams(term string) []string {
+	runes := []rune(" " + term + " ")
+	trigrams := make([]string, 0, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		trigrams = append(trigrams, string(runes[i:i+3]))
+	}
+	return trigrams
+}
+
+func isNumeric(term string) bool {
+	for _, r := range term {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance - расстояние Дамерау-Левенштейна (с перестановкой соседних букв).
+// Если оно заведомо больше max, возвращается max+1.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"testing"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestSearchIndex(t *testing.T) {
+	idx := newSearchIndex([]*entity.Address{
+		{City: "Москва", Street: "Тверская", House: "1"},
+		{City: "Москва", Street: "Тверская", House: "12"},
+		{City: "Москва", Street: "Тверской бульвар", House: "1"},
+		{City: "Санкт-Петербург", Street: "Невский проспект", House: "28"},
+		{City: "Москва", Street: "Щёлковское шоссе", House: "3"},
+	})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string // "улица дом" в порядке выдачи
+	}{
+		{name: "exact", query: "Москва, ул. Тверская, д. 12", want: []string{"Тверская 12"}},
+		{name: "prefix", query: "твер 1", want: []string{"Тверская 1", "Тверской бульвар 1", "Тверская 12"}},
+		{name: "typo", query: "невскй 28", want: []string{"Невский проспект 28"}},
+		{name: "transposition", query: "Сакнт-Петербург", want: []string{"Невский проспект 28"}},
+		{name: "yo", query: "щелковское", want: []string{"Щёлковское шоссе 3"}},
+		{name: "house numbers are not fuzzy", query: "Тверская 13", want: nil},
+		{name: "every word must match", query: "Тверская Невский", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.search(tt.query, 10)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %d results", tt.want, len(got))
+			}
+			for i, address := range got {
+				if s := address.Street + " " + address.House; s != tt.want[i] {
+					t.Errorf("result %d: expected %q, got %q", i, tt.want[i], s)
+				}
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"тверская", "тверская", 0},
+		{"тверская", "тверкая", 1},
+		{"сакнт", "санкт", 1},
+		{"москва", "питер", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, 2); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
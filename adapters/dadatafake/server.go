@@ -0,0 +1,377 @@
+// Package dadatafake - поддельный API подсказок DaData поверх httptest для тестов без интернета.
+// Поддерживает suggest/address и geolocate/address, а также ошибки и медленные ответы.
+package dadatafake
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// apiPath - префикс API, как у настоящего suggestions.dadata.ru
+const apiPath = "/suggestions/api/4_1/rs/"
+
+// Failure - режим отказа, которым сервер отвечает вместо данных
+type Failure int
+
+const (
+	FailNone Failure = iota
+	// FailUnauthorized - 401, неверный ключ API
+	FailUnauthorized
+	// FailForbidden - 403, исчерпан дневной лимит
+	FailForbidden
+	// FailTooManyRequests - 429, превышена частота запросов
+	FailTooManyRequests
+	// FailInternal - 500
+	FailInternal
+	// FailMalformed - 200 с обрезанным JSON
+	FailMalformed
+)
+
+// Address - адрес в данных фейка
+type Address struct {
+	PostalCode string
+	City       string
+	// StreetType - "ул", "пл", "пр-кт"; пустой значит "ул"
+	StreetType string
+	Street     string
+	House      string
+	Lat        float64
+	Lon        float64
+}
+
+// DefaultAddresses - набор адресов, с которым сервер создаётся без WithAddresses
+var DefaultAddresses = []Address{
+	{PostalCode: "125009", City: "Москва", Street: "Тверская", House: "1", Lat: 55.7573, Lon: 37.6131},
+	{PostalCode: "125009", City: "Москва", Street: "Тверская", House: "10", Lat: 55.7601, Lon: 37.6085},
+	{PostalCode: "109012", City: "Москва", StreetType: "пл", Street: "Красная", House: "1", Lat: 55.7539, Lon: 37.6208},
+	{PostalCode: "191186", City: "Санкт-Петербург", StreetType: "пр-кт", Street: "Невский", House: "1", Lat: 59.9369, Lon: 30.3140},
+}
+
+// Request - запрос, полученный сервером
+type Request struct {
+	Path          string
+	Authorization string
+	Body          json.RawMessage
+}
+
+// Server - поддельный DaData. Создаётся через New, закрывается через Close.
+type Server struct {
+	srv    *httptest.Server
+	apiKey string
+
+	mu        sync.Mutex
+	addresses []Address
+	failure   Failure
+	// failLeft - сколько ещё запросов отвечать failure; 0 - пока не сбросят
+	failLeft int
+	delay    time.Duration
+	requests []Request
+}
+
+// Option настраивает Server
+type Option func(*Server)
+
+// WithAPIKey требует заголовок "Authorization: Token <key>", иначе отвечает 401
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// WithAddresses заменяет DefaultAddresses
+func WithAddresses(addresses ...Address) Option {
+	return func(s *Server) {
+		s.addresses = append([]Address(nil), addresses...)
+	}
+}
+
+func New(opts ...Option) *Server {
+	s := &Server{addresses: append([]Address(nil), DefaultAddresses...)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// BaseURL - адрес для repository.WithBaseURL
+func (s *Server) BaseURL() string {
+	return s.srv.URL + apiPath
+}
+
+// Client - HTTP-клиент сервера
+func (s *Server) Client() *http.Client {
+	return s.srv.Client()
+}
+
+// Add добавляет адреса к данным сервера
+func (s *Server) Add(addresses ...Address) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addresses = append(s.addresses, addresses...)
+}
+
+// SetFailure включает режим отказа для всех следующих запросов; FailNone выключает
+func (s *Server) SetFailure(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure, s.failLeft = f, 0
+}
+
+// FailNext отвечает failure на n следующих запросов, затем снова отвечает данными
+func (s *Server) FailNext(f Failure, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure, s.failLeft = f, n
+}
+
+// SetDelay задерживает каждый ответ на d. Клиент, закрывший соединение, не ждёт задержку.
+func (s *Server) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// Requests возвращает полученные запросы по порядку
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && r.Method == http.MethodPost {
+		writeError(w, http.StatusBadRequest, "Bad Request", "invalid JSON body")
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Path: r.URL.Path, Authorization: r.Header.Get("Authorization"), Body: body})
+	failure, delay := s.failure, s.delay
+	if s.failLeft > 0 {
+		s.failLeft--
+		if s.failLeft == 0 {
+			s.failure = FailNone
+		}
+	}
+	addresses := s.addresses
+	s.mu.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Token "+s.apiKey {
+		failure = FailUnauthorized
+	}
+	switch failure {
+	case FailUnauthorized:
+		writeError(w, http.StatusUnauthorized, "Unauthorized", "Zero balance or invalid API key")
+		return
+	case FailForbidden:
+		writeError(w, http.StatusForbidden, "Forbidden", "Daily limit exceeded")
+		return
+	case FailTooManyRequests:
+		writeError(w, http.StatusTooManyRequests, "Too Many Requests", "Too many requests per second")
+		return
+	case FailInternal:
+		writeError(w, http.StatusInternalServerError, "Internal Server Error", "Internal server error")
+		return
+	case FailMalformed:
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.Write([]byte(`{"suggestions": [{"value": "г Москва`))
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", "only POST is supported")
+		return
+	}
+	switch strings.TrimPrefix(r.URL.Path, apiPath) {
+	case "suggest/address":
+		var req struct {
+			Query string `json:"query"`
+			Count int    `json:"count"`
+		}
+		json.Unmarshal(body, &req)
+		writeSuggestions(w, suggest(addresses, req.Query), req.Count)
+	case "geolocate/address":
+		var req struct {
+			Lat          float64 `json:"lat"`
+			Lon          float64 `json:"lon"`
+			RadiusMeters float64 `json:"radius_meters"`
+			Count        int     `json:"count"`
+		}
+		json.Unmarshal(body, &req)
+		writeSuggestions(w, geolocate(addresses, req.Lat, req.Lon, req.RadiusMeters), req.Count)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found", "unknown method "+r.URL.Path)
+	}
+}
+
+// suggest находит адреса, в которых каждое слово запроса - начало какого-то слова адреса
+func suggest(addresses []Address, query string) []Address {
+	terms := words(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	var res []Address
+	for _, address := range addresses {
+		addressWords := words(address.value())
+		matched := true
+		for _, term := range terms {
+			if !hasPrefixWord(addressWords, term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			res = append(res, address)
+		}
+	}
+	return res
+}
+
+// geolocate находит адреса в радиусе от точки, ближние первыми. Радиус по умолчанию
+// и максимальный - как у DaData: 100 и 1000 метров.
+func geolocate(addresses []Address, lat, lon, radius float64) []Address {
+	if radius <= 0 {
+		radius = 100
+	}
+	radius = math.Min(radius, 1000)
+	var res []Address
+	for _, address := range addresses {
+		if distance(lat, lon, address.Lat, address.Lon) <= radius {
+			res = append(res, address)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return distance(lat, lon, res[i].Lat, res[i].Lon) < distance(lat, lon, res[j].Lat, res[j].Lon)
+	})
+	return res
+}
+
+func writeSuggestions(w http.ResponseWriter, addresses []Address, count int) {
+	if count <= 0 {
+		count = 10
+	}
+	count = min(count, 20)
+	if len(addresses) > count {
+		addresses = addresses[:count]
+	}
+	suggestions := make([]suggestion, len(addresses))
+	for i, address := range addresses {
+		suggestions[i] = address.suggestion()
+	}
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{"suggestions": suggestions})
+}
+
+// writeError отвечает ошибкой в формате DaData
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	family := "CLIENT_ERROR"
+	if status >= 500 {
+		family = "SERVER_ERROR"
+	}
+	json.NewEncoder(w).Encode(map[string]string{"family": family, "reason": reason, "message": message})
+}
+
+type suggestion struct {
+	Value             string         `json:"value"`
+	UnrestrictedValue string         `json:"unrestricted_value"`
+	Data              suggestionData `json:"data"`
+}
+
+type suggestionData struct {
+	PostalCode     string `json:"postal_code"`
+	Country        string `json:"country"`
+	RegionWithType string `json:"region_with_type"`
+	City           string `json:"city"`
+	CityWithType   string `json:"city_with_type"`
+	Street         string `json:"street"`
+	StreetType     string `json:"street_type"`
+	StreetWithType string `json:"street_with_type"`
+	House          string `json:"house"`
+	HouseType      string `json:"house_type"`
+	GeoLat         string `json:"geo_lat"`
+	GeoLon         string `json:"geo_lon"`
+	QcGeo          string `json:"qc_geo"`
+}
+
+func (a Address) streetType() string {
+	if a.StreetType == "" {
+		return "ул"
+	}
+	return a.StreetType
+}
+
+func (a Address) value() string {
+	return "г " + a.City + ", " + a.streetType() + " " + a.Street + ", д " + a.House
+}
+
+func (a Address) suggestion() suggestion {
+	return suggestion{
+		Value:             a.value(),
+		UnrestrictedValue: a.PostalCode + ", " + a.value(),
+		Data: suggestionData{
+			PostalCode:     a.PostalCode,
+			Country:        "Россия",
+			RegionWithType: "г " + a.City,
+			City:           a.City,
+			CityWithType:   "г " + a.City,
+			Street:         a.Street,
+			StreetType:     a.streetType(),
+			StreetWithType: a.streetType() + " " + a.Street,
+			House:          a.House,
+			HouseType:      "д",
+			GeoLat:         strconv.FormatFloat(a.Lat, 'f', -1, 64),
+			GeoLon:         strconv.FormatFloat(a.Lon, 'f', -1, 64),
+			QcGeo:          "0",
+		},
+	}
+}
+
+func words(text string) []string {
+	text = strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func hasPrefixWord(words []string, prefix string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// distance - расстояние между точками в метрах по формуле гаверсинусов
+func distance(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,141 @@
+package dadatafake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func post(t *testing.T, s *Server, ctx context.Context, method, body string) (*http.Response, []suggestion) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, "POST", s.BaseURL()+method, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Token key")
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var res struct {
+		Suggestions []suggestion `json:"suggestions"`
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp, res.Suggestions
+}
+
+func TestSuggest(t *testing.T) {
+	s := New()
+	defer s.Close()
+	ctx := context.Background()
+
+	_, suggestions := post(t, s, ctx, "suggest/address", `{"query": "москва твер 1"}`)
+	if len(suggestions) != 2 || suggestions[0].Data.House != "1" || suggestions[1].Data.House != "10" {
+		t.Errorf("unexpected suggestions %+v", suggestions)
+	}
+	_, suggestions = post(t, s, ctx, "suggest/address", `{"query": "москва тверская", "count": 1}`)
+	if len(suggestions) != 1 {
+		t.Errorf("expected count to limit suggestions, got %d", len(suggestions))
+	}
+	_, suggestions = post(t, s, ctx, "suggest/address", `{"query": "нет такого"}`)
+	if len(suggestions) != 0 {
+		t.Errorf("expected no suggestions, got %+v", suggestions)
+	}
+	if got := s.Requests(); len(got) != 3 || got[0].Authorization != "Token key" {
+		t.Errorf("unexpected recorded requests %+v", got)
+	}
+}
+
+func TestGeolocate(t *testing.T) {
+	s := New()
+	defer s.Close()
+	ctx := context.Background()
+
+	_, suggestions := post(t, s, ctx, "geolocate/address", `{"lat": 55.7538, "lon": 37.6207}`)
+	if len(suggestions) != 1 || suggestions[0].Data.Street != "Красная" || suggestions[0].Data.GeoLat != "55.7539" {
+		t.Errorf("unexpected suggestions %+v", suggestions)
+	}
+	_, suggestions = post(t, s, ctx, "geolocate/address", `{"lat": 55.7575, "lon": 37.6131, "radius_meters": 1000}`)
+	if len(suggestions) != 3 || suggestions[0].Data.House != "1" || suggestions[0].Data.Street != "Тверская" {
+		t.Errorf("expected nearest first, got %+v", suggestions)
+	}
+	_, suggestions = post(t, s, ctx, "geolocate/address", `{"lat": 0, "lon": 0}`)
+	if len(suggestions) != 0 {
+		t.Errorf("expected no suggestions, got %+v", suggestions)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	s := New(WithAPIKey("secret"))
+	defer s.Close()
+	ctx := context.Background()
+
+	if resp, _ := post(t, s, ctx, "suggest/address", `{"query": "москва"}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for wrong key, got %d", resp.StatusCode)
+	}
+
+	s = New()
+	defer s.Close()
+	for failure, status := range map[Failure]int{
+		FailForbidden:       http.StatusForbidden,
+		FailTooManyRequests: http.StatusTooManyRequests,
+		FailInternal:        http.StatusInternalServerError,
+	} {
+		s.SetFailure(failure)
+		if resp, _ := post(t, s, ctx, "suggest/address", `{"query": "москва"}`); resp.StatusCode != status {
+			t.Errorf("expected %d, got %d", status, resp.StatusCode)
+		}
+	}
+
+	s.FailNext(FailInternal, 1)
+	if resp, _ := post(t, s, ctx, "suggest/address", `{"query": "москва"}`); resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected first request to fail, got %d", resp.StatusCode)
+	}
+	if _, suggestions := post(t, s, ctx, "suggest/address", `{"query": "москва"}`); len(suggestions) != 3 {
+		t.Errorf("expected server to recover after FailNext, got %+v", suggestions)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.SetFailure(FailMalformed)
+
+	resp, err := s.Client().Post(s.BaseURL()+"suggest/address", "application/json", strings.NewReader(`{"query": "москва"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var v interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&v); err == nil {
+		t.Error("expected malformed JSON")
+	}
+}
+
+func TestDelayHonoursContext(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.SetDelay(time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "POST", s.BaseURL()+"suggest/address", strings.NewReader(`{"query": "москва"}`))
+	start := time.Now()
+	_, err := s.Client().Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("expected client to stop waiting at its deadline")
+	}
+}
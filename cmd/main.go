@@ -6,6 +6,9 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"time"
@@ -15,6 +18,8 @@ import (
 	healthpoint "studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/Healthpoint"
 	myhttp "studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/http"
 	"studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/repository"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
 )
 
 // @title Address API
@@ -33,11 +38,69 @@ import (
 func main() {
 	logger, _ := zap.NewProduction()
 	defer logger.Sync()
-	geoService := repository.NewGeoService("d9e0649452a137b73d941aa4fb4fcac859372c8c", "ec99b849ebf21277ec821c63e1a2bc8221900b1d")
+	// GEO_PROVIDER - геопровайдеры через запятую в порядке опроса, например "dadata,nominatim".
+	// Адрес API каждого можно переопределить переменной <ИМЯ>_URL, например NOMINATIM_URL.
+	providerNames := strings.Split(envOr("GEO_PROVIDER", "dadata"), ",")
+	// GEO_LIMIT_POLICY - что делать при исчерпанных лимитах: queue, fail или stale
+	limitPolicy, err := repository.ParseLimitPolicy(envOr("GEO_LIMIT_POLICY", "queue"))
+	if err != nil {
+		logger.Fatal("Invalid GEO_LIMIT_POLICY", zap.Error(err))
+	}
+	httpClient := fixturesClient(logger)
+	geoService, err := repository.NewProviderChain(providerNames, func(name string) repository.ProviderConfig {
+		return repository.ProviderConfig{
+			APIKey:    envOr("DADATA_API_KEY", "d9e0649452a137b73d941aa4fb4fcac859372c8c"),
+			SecretKey: envOr("DADATA_SECRET_KEY", "ec99b849ebf21277ec821c63e1a2bc8221900b1d"),
+			BaseURL:   os.Getenv(strings.ToUpper(name) + "_URL"),
+			UserAgent: os.Getenv("GEO_PROVIDER_USER_AGENT"),
+			// GEO_FIXTURES - демо-режим на записанных ответах, см. fixturesClient
+			HTTPClient: httpClient,
+			// OFFLINE_DATA - CSV или GeoJSON с адресами для провайдера offline
+			DataFile: os.Getenv(strings.ToUpper(name) + "_DATA"),
+			Radius:   envFloat("OFFLINE_RADIUS", 0, logger),
+			// Пять ошибок подряд отключают метод провайдера на 30 секунд
+			Breaker: adapter.BreakerConfig{FailureThreshold: 5, CoolDown: 30 * time.Second},
+			Limit:   limitConfig(name, limitPolicy, logger),
+			// <ИМЯ>_TIMEOUT - предел одного вызова провайдера, после которого запрос уходит
+			// следующему. Сумма по цепочке должна укладываться в GEO_TIMEOUT_*.
+			Timeout: envDuration(strings.ToUpper(name)+"_TIMEOUT", 2*time.Second, logger),
+		}
+	})
+	if err != nil {
+		logger.Fatal("Geo provider init failed", zap.Error(err))
+	}
+	logger.Info("Using geo providers", zap.Strings("providers", providerNames))
 	resp := repository.NewResponder(logger)
-	cache := adapter.NewCache(5 * time.Minute) // Создаем кэш с TTL 5 минут
+	// ADMIN_USERS - список пользователей через запятую с доступом к /api/admin.
+	// ADMIN_PASSWORD_HASH - bcrypt-хеш их пароля; обязателен, если задан ADMIN_USERS.
+	for _, name := range strings.Split(os.Getenv("ADMIN_USERS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			if err := usecase.AddAdmin(name, os.Getenv("ADMIN_PASSWORD_HASH")); err != nil {
+				logger.Fatal("Invalid admin account", zap.Error(err))
+			}
+		}
+	}
+	usecase.SetCachePolicy(usecase.CachePolicy{NegativeTTL: 30 * time.Second})
+	usecase.SetBatchLimits(usecase.BatchLimits{
+		MaxItems:    envInt("GEO_BATCH_MAX_ITEMS", 1000, logger),
+		Concurrency: envInt("GEO_BATCH_CONCURRENCY", 8, logger),
+	})
+	usecase.SetStreamConcurrency(envInt("GEO_STREAM_CONCURRENCY", 4, logger))
+	// GEO_TIMEOUT_GEOCODE и GEO_TIMEOUT_SEARCH - дедлайны вызова провайдера, например 3s.
+	// Они должны укладываться в WriteTimeout сервера.
+	usecase.SetTimeouts(usecase.Timeouts{
+		Geocode: envDuration("GEO_TIMEOUT_GEOCODE", 5*time.Second, logger),
+		Search:  envDuration("GEO_TIMEOUT_SEARCH", 5*time.Second, logger),
+	})
+	// GEO_KEY_STRATEGY - снапинг координат для ключей кэша, например geohash:7 или radius:50
+	if err := usecase.ConfigureGeoKeyStrategy(os.Getenv("GEO_KEY_STRATEGY")); err != nil {
+		logger.Fatal("Invalid GEO_KEY_STRATEGY", zap.Error(err))
+	}
+	cache, closeCache := newCache(logger)
+
+	jobs := newJobManager(geoService, cache, logger)
 
-	r := myhttp.Router(resp, geoService, cache)
+	r := myhttp.Router(resp, geoService, cache, myhttp.WithJobs(jobs))
 
 	// Создаем экземпляр entity.Server
 	srv := &adapter.Server{
@@ -55,6 +118,11 @@ func main() {
 	// Запускаем сервер в горутине
 	go srv.Serve()
 	gracefulShutdown(srv, logger)
+	jobs.Close()
+	if err := geoService.Close(); err != nil {
+		logger.Error("Geo provider close failed", zap.Error(err))
+	}
+	closeCache()
 	// Передаем экземпляр entity.Server в функции healthpoint
 	healthpoint.Healthpoint(cache, geoService)
 	healthpoint.Geopoint(srv) // Теперь передаем srv как *entity.Server
@@ -78,3 +146,195 @@ func gracefulShutdown(server *adapter.Server, logger *zap.Logger) {
 		logger.Info("Server stopped gracefully")
 	}
 }
+
+// newCache выбирает бэкенд кэша по переменной окружения CACHE_BACKEND (memory, redis или tiered)
+func newCache(logger *zap.Logger) (entity.Cache, func()) {
+	switch os.Getenv("CACHE_BACKEND") {
+	case "redis":
+		redisCache := newRedisCache(logger)
+		return redisCache, func() { redisCache.Close() }
+	case "tiered":
+		// Небольшой L1 в процессе перед общим для всех реплик Redis
+		l1 := adapter.NewCache(30*time.Second, adapter.WithMaxEntries(2000))
+		redisCache := newRedisCache(logger)
+		// Удаления из кэша рассылаются репликам через Redis pub/sub
+		tiered := adapter.NewTieredCache(l1, redisCache)
+		return tiered, func() {
+			tiered.Close()
+			l1.Close()
+			redisCache.Close()
+		}
+	default:
+		// Кэш с TTL CACHE_TTL (по умолчанию 5 минут), ограниченный по количеству записей и объёму.
+		// Устаревшие записи ещё минуту отдаются клиентам, пока обновляются в фоне.
+		cache := adapter.NewCache(envDuration("CACHE_TTL", 5*time.Minute, logger),
+			adapter.WithMaxEntries(10000),
+			adapter.WithMaxBytes(64<<20),
+			adapter.WithStaleGrace(time.Minute),
+		)
+
+		// Снимок кэша переживает перезапуск, чтобы не тратить квоту DaData повторно
+		snapshotPath := os.Getenv("CACHE_SNAPSHOT")
+		if snapshotPath == "" {
+			snapshotPath = "cache.snapshot"
+		}
+		// Снимок перезаписывается раз в минуту, поэтому писать в него может только один процесс
+		unlock, err := adapter.LockSnapshot(snapshotPath)
+		if err != nil {
+			logger.Fatal("Cache snapshot is in use", zap.Error(err))
+		}
+		if loaded, err := cache.LoadFile(snapshotPath); err != nil {
+			logger.Warn("Cache snapshot load failed", zap.Error(err))
+		} else {
+			logger.Info("Cache snapshot loaded", zap.Int("entries", loaded))
+		}
+		cache.StartSnapshots(snapshotPath, time.Minute)
+
+		return adapter.NewMemoryCache(cache), func() {
+			if err := cache.SaveFile(snapshotPath); err != nil {
+				logger.Error("Cache snapshot save failed", zap.Error(err))
+			}
+			cache.Close()
+			unlock()
+		}
+	}
+}
+
+func newRedisCache(logger *zap.Logger) *adapter.RedisCache {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	logger.Info("Using redis cache", zap.String("addr", addr))
+	return adapter.NewRedisCache(addr,
+		adapter.WithRedisPassword(os.Getenv("REDIS_PASSWORD")),
+		adapter.WithRedisKeyPrefix(envOr("REDIS_KEY_PREFIX", adapter.DefaultRedisKeyPrefix)),
+		adapter.WithRedisTTL(envDuration("CACHE_TTL", 5*time.Minute, logger)),
+		adapter.WithRedisStaleGrace(time.Minute),
+	)
+}
+
+// newJobManager запускает асинхронные задания. Их состояние хранится в JOBS_DIR,
+// незавершённые задания продолжаются после перезапуска. JOBS_WORKERS - сколько элементов
+// выполняется одновременно, JOBS_RATE - ограничение элементов в секунду.
+func newJobManager(geoService entity.GeoProvider, cache entity.Cache, logger *zap.Logger) *usecase.JobManager {
+	store, err := adapter.NewFileJobStore(envOr("JOBS_DIR", "jobs"))
+	if err != nil {
+		logger.Fatal("Job store init failed", zap.Error(err))
+	}
+	opts := usecase.DefaultJobOptions
+	opts.Workers = envInt("JOBS_WORKERS", opts.Workers, logger)
+	opts.Rate = envFloat("JOBS_RATE", 0, logger)
+	opts.Concurrent = envInt("JOBS_CONCURRENT", opts.Concurrent, logger)
+	opts.Retention = envDuration("JOBS_RETENTION", opts.Retention, logger)
+	jobs, err := usecase.NewJobManager(store, geoService, cache, opts)
+	if err != nil {
+		logger.Fatal("Job manager init failed", zap.Error(err))
+	}
+	return jobs
+}
+
+// fixturesClient включает демо-режим: с GEO_FIXTURES=<каталог> провайдеры отвечают записанными
+// фикстурами без сети и ключей API, а с GEO_FIXTURES_MODE=record - ходят в сеть и пишут фикстуры.
+// Без GEO_FIXTURES возвращает nil, то есть http.DefaultClient.
+func fixturesClient(logger *zap.Logger) *http.Client {
+	dir := os.Getenv("GEO_FIXTURES")
+	if dir == "" {
+		return nil
+	}
+	if os.Getenv("GEO_FIXTURES_MODE") == "record" {
+		logger.Info("Recording geo provider fixtures", zap.String("dir", dir))
+		return &http.Client{Transport: &adapter.RecordingTransport{Dir: dir}}
+	}
+	transport, err := adapter.NewReplayTransport(dir)
+	if err != nil {
+		logger.Fatal("Geo provider fixtures load failed", zap.Error(err))
+	}
+	logger.Info("Demo mode: replaying geo provider fixtures", zap.String("dir", dir), zap.Int("fixtures", transport.Len()))
+	return &http.Client{Transport: transport}
+}
+
+// limitConfig читает лимиты провайдера из <ИМЯ>_RATE, <ИМЯ>_BURST и <ИМЯ>_DAILY_QUOTA.
+// По умолчанию DaData - 20 запросов в секунду и 10000 в сутки (бесплатный тариф),
+// Nominatim - 1 запрос в секунду по правилам публичного сервера.
+// Счётчики квот хранятся в каталоге QUOTA_DIR.
+func limitConfig(name string, policy repository.LimitPolicy, logger *zap.Logger) repository.LimitConfig {
+	cfg := repository.LimitConfig{Policy: policy}
+	switch name {
+	case "dadata":
+		cfg.Rate, cfg.Burst, cfg.DailyQuota = 20, 20, 10000
+		// Квота DaData сбрасывается в полночь по Москве
+		cfg.QuotaLocation = time.FixedZone("MSK", 3*60*60)
+	case "nominatim":
+		cfg.Rate, cfg.Burst = 1, 1
+	}
+	prefix := strings.ToUpper(name)
+	if v := os.Getenv(prefix + "_RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			logger.Fatal("Invalid "+prefix+"_RATE", zap.Error(err))
+		}
+		cfg.Rate = rate
+	}
+	if v := os.Getenv(prefix + "_BURST"); v != "" {
+		burst, err := strconv.Atoi(v)
+		if err != nil {
+			logger.Fatal("Invalid "+prefix+"_BURST", zap.Error(err))
+		}
+		cfg.Burst = burst
+	}
+	if v := os.Getenv(prefix + "_DAILY_QUOTA"); v != "" {
+		quota, err := strconv.Atoi(v)
+		if err != nil {
+			logger.Fatal("Invalid "+prefix+"_DAILY_QUOTA", zap.Error(err))
+		}
+		cfg.DailyQuota = quota
+	}
+	if cfg.DailyQuota > 0 {
+		cfg.QuotaFile = filepath.Join(envOr("QUOTA_DIR", "."), "quota-"+name+".json")
+	}
+	return cfg
+}
+
+func envFloat(key string, fallback float64, logger *zap.Logger) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		logger.Fatal("Invalid "+key, zap.Error(err))
+	}
+	return f
+}
+
+func envInt(key string, fallback int, logger *zap.Logger) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		logger.Fatal("Invalid "+key, zap.Error(err))
+	}
+	return n
+}
+
+func envDuration(key string, fallback time.Duration, logger *zap.Logger) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		logger.Fatal("Invalid "+key, zap.Error(err))
+	}
+	return d
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/controllers/controller/repository"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/usecase"
+)
+
+// Прогрев кэша списком запросов. По умолчанию результат пишется в снимок кэша,
+// который сервер загрузит при старте; с CACHE_BACKEND=redis - сразу в общий Redis.
+// Пока сервер работает, снимком владеет он: прогрев в память откажется запускаться,
+// используйте POST /api/admin/cache/warm или CACHE_BACKEND=redis.
+// GEO_KEY_STRATEGY и CACHE_TTL должны совпадать с настройками сервера. В снимке хранится
+// оставшееся время жизни записей, поэтому прогрев можно запускать задолго до старта сервера.
+//
+//	go run ./cmd/warm -file queries.txt -concurrency 4 -rate 10
+func main() {
+	file := flag.String("file", "", "файл со списком запросов: строка поиска или \"lat,lng\" на строку")
+	concurrency := flag.Int("concurrency", 4, "количество одновременных запросов")
+	rate := flag.Float64("rate", 10, "максимум вызовов провайдера в секунду, 0 - без ограничения")
+	snapshot := flag.String("snapshot", "cache.snapshot", "файл снимка кэша для backend memory")
+	flag.Parse()
+
+	if *file == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := usecase.ConfigureGeoKeyStrategy(os.Getenv("GEO_KEY_STRATEGY")); err != nil {
+		log.Fatalf("GEO_KEY_STRATEGY: %v", err)
+	}
+	f, err := os.Open(*file)
+	if err != nil {
+		log.Fatalf("open %s: %v", *file, err)
+	}
+	items, err := usecase.ParseWarmList(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("read %s: %v", *file, err)
+	}
+
+	// Прогретые записи живут столько же, сколько записи сервера
+	ttl := 5 * time.Minute
+	if v := os.Getenv("CACHE_TTL"); v != "" {
+		if ttl, err = time.ParseDuration(v); err != nil || ttl <= 0 {
+			log.Fatalf("CACHE_TTL: invalid duration %q", v)
+		}
+	}
+
+	providerNames := os.Getenv("GEO_PROVIDER")
+	if providerNames == "" {
+		providerNames = "dadata"
+	}
+	geoService, err := repository.NewProviderChain(strings.Split(providerNames, ","), func(name string) repository.ProviderConfig {
+		return repository.ProviderConfig{
+			APIKey:    os.Getenv("DADATA_API_KEY"),
+			SecretKey: os.Getenv("DADATA_SECRET_KEY"),
+			BaseURL:   os.Getenv(strings.ToUpper(name) + "_URL"),
+			UserAgent: os.Getenv("GEO_PROVIDER_USER_AGENT"),
+			DataFile:  os.Getenv(strings.ToUpper(name) + "_DATA"),
+		}
+	})
+	if err != nil {
+		log.Fatalf("geo provider: %v", err)
+	}
+
+	var cache entity.Cache
+	var save func() error
+	switch os.Getenv("CACHE_BACKEND") {
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		prefix := os.Getenv("REDIS_KEY_PREFIX")
+		if prefix == "" {
+			prefix = adapter.DefaultRedisKeyPrefix
+		}
+		redisCache := adapter.NewRedisCache(addr,
+			adapter.WithRedisPassword(os.Getenv("REDIS_PASSWORD")),
+			adapter.WithRedisKeyPrefix(prefix),
+			adapter.WithRedisTTL(ttl),
+		)
+		defer redisCache.Close()
+		cache = redisCache
+		save = func() error { return nil }
+	default:
+		// Работающий сервер перезапишет снимок своим кэшем, и прогрев пропадёт
+		unlock, err := adapter.LockSnapshot(*snapshot)
+		if err != nil {
+			log.Fatalf("%v: stop the server or warm it through POST /api/admin/cache/warm", err)
+		}
+		defer unlock()
+		memory := adapter.NewCache(ttl)
+		defer memory.Close()
+		if loaded, err := memory.LoadFile(*snapshot); err != nil {
+			log.Fatalf("load snapshot: %v", err)
+		} else {
+			log.Printf("loaded %d cached entries from %s", loaded, *snapshot)
+		}
+		cache = adapter.NewMemoryCache(memory)
+		save = func() error { return memory.SaveFile(*snapshot) }
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	report := usecase.Warm(ctx, items, geoService, cache, usecase.WarmOptions{
+		Concurrency: *concurrency,
+		Rate:        *rate,
+		Progress: func(r usecase.WarmReport) {
+			if r.Done%100 == 0 || r.Done == r.Total {
+				log.Printf("progress: %d/%d, failed %d, upstream calls %d", r.Done, r.Total, r.Failed, r.UpstreamCalls)
+			}
+		},
+	})
+
+	if err := save(); err != nil {
+		log.Fatalf("save snapshot: %v", err)
+	}
+	for _, e := range report.Errors {
+		log.Printf("failed %q: %s", e.Item, e.Error)
+	}
+	log.Printf("done in %s: %d/%d warmed, %d failed, %d upstream calls",
+		time.Since(start).Round(time.Second), report.Done-report.Failed, report.Total, report.Failed, report.UpstreamCalls)
+}
@@ -1,12 +1,18 @@
 package entity
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type GeoProvider interface {
-	AddressSearch(input string) ([]*Address, error)
-	GeoCode(lat, lng string) ([]*Address, error)
-	GetGeoCoordinatesAddress(query string) (ResponseAddresses, error)
-	GetGeoCoordinatesGeocode(lat float64, lng float64) (ResponseAddresses, error)
+	AddressSearch(ctx context.Context, input string) ([]*Address, error)
+	GeoCode(ctx context.Context, lat, lng string) ([]*Address, error)
+	GetGeoCoordinatesAddress(ctx context.Context, query string) (ResponseAddresses, error)
+	GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (ResponseAddresses, error)
 }
 
 type Response struct {
@@ -16,16 +22,152 @@ type Response struct {
 }
 
 type GeocodeRequest struct {
-	Lat float64 `json:"lat"`
-	Lng float64 `json:"lng"`
+	Lat Coordinate `json:"lat"`
+	Lng Coordinate `json:"lng"`
+}
+
+// UnmarshalJSON требует оба поля: без этого отсутствующая координата стала бы нулём,
+// а точка 0,0 - допустимая.
+func (r *GeocodeRequest) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Lat json.RawMessage `json:"lat"`
+		Lng json.RawMessage `json:"lng"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := r.Lat.unmarshalField(raw.Lat, "lat"); err != nil {
+		return err
+	}
+	return r.Lng.unmarshalField(raw.Lng, "lng")
+}
+
+// Validate проверяет диапазоны координат запроса
+func (r GeocodeRequest) Validate() error {
+	return ValidatePoint(r.Lat, r.Lng, "lat", "lng")
 }
 
 type Address struct {
 	City   string `json:"city"`
 	Street string `json:"street"`
 	House  string `json:"house"`
-	Lat    string `json:"geo_lat"`
-	Lon    string `json:"geo_lon"`
+	// Lat и Lon заданы, только если HasCoordinates; заполняются через SetCoordinates.
+	// В JSON координаты отдаются строками, как их присылают провайдеры, и пустыми
+	// строками, если их нет.
+	Lat            Coordinate `json:"geo_lat"`
+	Lon            Coordinate `json:"geo_lon"`
+	HasCoordinates bool       `json:"-"`
+	// Details - полный адрес от провайдера, отдаётся только с DetailFull
+	Details *Data `json:"details,omitempty"`
+}
+
+// SetCoordinates разбирает и проверяет координаты в строковом виде провайдера.
+// Две пустые строки - адрес без координат. При ошибке координаты сбрасываются.
+func (a *Address) SetCoordinates(lat, lon string) error {
+	a.Lat, a.Lon, a.HasCoordinates = 0, 0, false
+	if strings.TrimSpace(lat) == "" && strings.TrimSpace(lon) == "" {
+		return nil
+	}
+	latValue, err := ParseCoordinate(lat)
+	if err != nil {
+		return &FieldError{Field: "geo_lat", Message: err.Error()}
+	}
+	lonValue, err := ParseCoordinate(lon)
+	if err != nil {
+		return &FieldError{Field: "geo_lon", Message: err.Error()}
+	}
+	if strings.TrimSpace(lat) == "" || strings.TrimSpace(lon) == "" {
+		return &FieldError{Field: "geo_lat", Message: "geo_lat and geo_lon must be set together"}
+	}
+	if err := ValidatePoint(latValue, lonValue, "geo_lat", "geo_lon"); err != nil {
+		return err
+	}
+	a.Lat, a.Lon, a.HasCoordinates = latValue, lonValue, true
+	return nil
+}
+
+// Coordinates возвращает координаты в строковом виде; пустые строки, если координат нет
+func (a Address) Coordinates() (lat, lon string) {
+	if !a.HasCoordinates {
+		return "", ""
+	}
+	return a.Lat.String(), a.Lon.String()
+}
+
+// addressJSON - Address в формате API
+type addressJSON struct {
+	City    string     `json:"city"`
+	Street  string     `json:"street"`
+	House   string     `json:"house"`
+	Lat     FlexString `json:"geo_lat"`
+	Lon     FlexString `json:"geo_lon"`
+	Details *Data      `json:"details,omitempty"`
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	lat, lon := a.Coordinates()
+	return json.Marshal(addressJSON{City: a.City, Street: a.Street, House: a.House,
+		Lat: FlexString(lat), Lon: FlexString(lon), Details: a.Details})
+}
+
+// UnmarshalJSON принимает координаты и строками, и числами
+func (a *Address) UnmarshalJSON(data []byte) error {
+	var v addressJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = Address{City: v.City, Street: v.Street, House: v.House, Details: v.Details}
+	return a.SetCoordinates(string(v.Lat), string(v.Lon))
+}
+
+// DetailLevel - подробность адресов в ответе API
+type DetailLevel string
+
+const (
+	// DetailBasic - город, улица, дом и координаты
+	DetailBasic DetailLevel = "basic"
+	// DetailFull - ещё и полный адрес провайдера в Details
+	DetailFull DetailLevel = "full"
+)
+
+// ParseDetailLevel разбирает параметр detail; пустая строка - DetailBasic
+func ParseDetailLevel(s string) (DetailLevel, error) {
+	switch DetailLevel(s) {
+	case "", DetailBasic:
+		return DetailBasic, nil
+	case DetailFull:
+		return DetailFull, nil
+	}
+	return "", fmt.Errorf("unknown detail level %q, expected %q or %q", s, DetailBasic, DetailFull)
+}
+
+// CachedError - ошибка провайдера, сохранённая в кэше на короткий срок. Для неуспешного
+// ответа провайдера хранит и его статус: Unwrap восстанавливает *UpstreamError, чтобы
+// ошибка из кэша давала клиенту тот же HTTP-статус, что и исходная.
+type CachedError struct {
+	Message    string `json:"message"`
+	Provider   string `json:"provider,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+}
+
+func NewCachedError(err error) CachedError {
+	cached := CachedError{Message: err.Error()}
+	var upstream *UpstreamError
+	if errors.As(err, &upstream) {
+		cached.Provider, cached.StatusCode = upstream.Provider, upstream.StatusCode
+	}
+	return cached
+}
+
+func (e CachedError) Error() string {
+	return e.Message
+}
+
+func (e CachedError) Unwrap() error {
+	if e.StatusCode == 0 {
+		return nil
+	}
+	return &UpstreamError{Provider: e.Provider, StatusCode: e.StatusCode}
 }
 
 type TokenResponse struct {
@@ -44,6 +186,24 @@ type RequestAddressSearch struct {
 
 type ResponseAddresses struct {
 	Addresses []*Address `json:"addresses"`
+	// Provider - имя провайдера, который дал ответ; отдаётся в заголовке X-Geo-Provider
+	Provider string `json:"-"`
+}
+
+// WithDetail возвращает ответ с нужной подробностью. Адреса копируются, чтобы не менять
+// ответ, который лежит в кэше.
+func (r ResponseAddresses) WithDetail(level DetailLevel) ResponseAddresses {
+	if level == DetailFull {
+		return r
+	}
+	res := r
+	res.Addresses = make([]*Address, len(r.Addresses))
+	for i, address := range r.Addresses {
+		basic := *address
+		basic.Details = nil
+		res.Addresses[i] = &basic
+	}
+	return res
 }
 
 type ResponseAddress struct {
@@ -84,69 +244,69 @@ type Data struct {
 	RegionType           Type            `json:"region_type"`
 	RegionTypeFull       TypeFull        `json:"region_type_full"`
 	Region               City            `json:"region"`
-	AreaFiasID           interface{}     `json:"area_fias_id"`
-	AreaKladrID          interface{}     `json:"area_kladr_id"`
-	AreaWithType         interface{}     `json:"area_with_type"`
-	AreaType             interface{}     `json:"area_type"`
-	AreaTypeFull         interface{}     `json:"area_type_full"`
-	Area                 interface{}     `json:"area"`
+	AreaFiasID           string          `json:"area_fias_id"`
+	AreaKladrID          string          `json:"area_kladr_id"`
+	AreaWithType         string          `json:"area_with_type"`
+	AreaType             string          `json:"area_type"`
+	AreaTypeFull         string          `json:"area_type_full"`
+	Area                 string          `json:"area"`
 	CityFiasID           string          `json:"city_fias_id"`
 	CityKladrID          string          `json:"city_kladr_id"`
 	CityWithType         WithType        `json:"city_with_type"`
 	CityType             Type            `json:"city_type"`
 	CityTypeFull         TypeFull        `json:"city_type_full"`
 	City                 City            `json:"city"`
-	CityArea             interface{}     `json:"city_area"`
-	CityDistrictFiasID   interface{}     `json:"city_district_fias_id"`
-	CityDistrictKladrID  interface{}     `json:"city_district_kladr_id"`
-	CityDistrictWithType interface{}     `json:"city_district_with_type"`
-	CityDistrictType     interface{}     `json:"city_district_type"`
-	CityDistrictTypeFull interface{}     `json:"city_district_type_full"`
-	CityDistrict         interface{}     `json:"city_district"`
-	SettlementFiasID     interface{}     `json:"settlement_fias_id"`
-	SettlementKladrID    interface{}     `json:"settlement_kladr_id"`
-	SettlementWithType   interface{}     `json:"settlement_with_type"`
-	SettlementType       interface{}     `json:"settlement_type"`
-	SettlementTypeFull   interface{}     `json:"settlement_type_full"`
-	Settlement           interface{}     `json:"settlement"`
+	CityArea             string          `json:"city_area"`
+	CityDistrictFiasID   string          `json:"city_district_fias_id"`
+	CityDistrictKladrID  string          `json:"city_district_kladr_id"`
+	CityDistrictWithType string          `json:"city_district_with_type"`
+	CityDistrictType     string          `json:"city_district_type"`
+	CityDistrictTypeFull string          `json:"city_district_type_full"`
+	CityDistrict         string          `json:"city_district"`
+	SettlementFiasID     string          `json:"settlement_fias_id"`
+	SettlementKladrID    string          `json:"settlement_kladr_id"`
+	SettlementWithType   string          `json:"settlement_with_type"`
+	SettlementType       string          `json:"settlement_type"`
+	SettlementTypeFull   string          `json:"settlement_type_full"`
+	Settlement           string          `json:"settlement"`
 	StreetFiasID         string          `json:"street_fias_id"`
 	StreetKladrID        string          `json:"street_kladr_id"`
 	StreetWithType       StreetWithType  `json:"street_with_type"`
 	StreetType           StreetType      `json:"street_type"`
 	StreetTypeFull       StreetTypeFull  `json:"street_type_full"`
 	Street               Street          `json:"street"`
-	SteadFiasID          interface{}     `json:"stead_fias_id"`
-	SteadCadnum          interface{}     `json:"stead_cadnum"`
-	SteadType            interface{}     `json:"stead_type"`
-	SteadTypeFull        interface{}     `json:"stead_type_full"`
-	Stead                interface{}     `json:"stead"`
+	SteadFiasID          string          `json:"stead_fias_id"`
+	SteadCadnum          string          `json:"stead_cadnum"`
+	SteadType            string          `json:"stead_type"`
+	SteadTypeFull        string          `json:"stead_type_full"`
+	Stead                string          `json:"stead"`
 	HouseFiasID          *string         `json:"house_fias_id"`
 	HouseKladrID         *string         `json:"house_kladr_id"`
-	HouseCadnum          interface{}     `json:"house_cadnum"`
+	HouseCadnum          string          `json:"house_cadnum"`
 	HouseType            HouseType       `json:"house_type"`
 	HouseTypeFull        HouseTypeFull   `json:"house_type_full"`
 	House                string          `json:"house"`
 	BlockType            *string         `json:"block_type"`
 	BlockTypeFull        *string         `json:"block_type_full"`
 	Block                *string         `json:"block"`
-	Entrance             interface{}     `json:"entrance"`
-	Floor                interface{}     `json:"floor"`
-	FlatFiasID           interface{}     `json:"flat_fias_id"`
-	FlatCadnum           interface{}     `json:"flat_cadnum"`
-	FlatType             interface{}     `json:"flat_type"`
-	FlatTypeFull         interface{}     `json:"flat_type_full"`
-	Flat                 interface{}     `json:"flat"`
-	FlatArea             interface{}     `json:"flat_area"`
-	SquareMeterPrice     interface{}     `json:"square_meter_price"`
-	FlatPrice            interface{}     `json:"flat_price"`
-	RoomFiasID           interface{}     `json:"room_fias_id"`
-	RoomCadnum           interface{}     `json:"room_cadnum"`
-	RoomType             interface{}     `json:"room_type"`
-	RoomTypeFull         interface{}     `json:"room_type_full"`
-	Room                 interface{}     `json:"room"`
-	PostalBox            interface{}     `json:"postal_box"`
+	Entrance             string          `json:"entrance"`
+	Floor                string          `json:"floor"`
+	FlatFiasID           string          `json:"flat_fias_id"`
+	FlatCadnum           string          `json:"flat_cadnum"`
+	FlatType             string          `json:"flat_type"`
+	FlatTypeFull         string          `json:"flat_type_full"`
+	Flat                 string          `json:"flat"`
+	FlatArea             FlexString      `json:"flat_area"`
+	SquareMeterPrice     FlexString      `json:"square_meter_price"`
+	FlatPrice            FlexString      `json:"flat_price"`
+	RoomFiasID           string          `json:"room_fias_id"`
+	RoomCadnum           string          `json:"room_cadnum"`
+	RoomType             string          `json:"room_type"`
+	RoomTypeFull         string          `json:"room_type_full"`
+	Room                 string          `json:"room"`
+	PostalBox            string          `json:"postal_box"`
 	FiasID               string          `json:"fias_id"`
-	FiasCode             interface{}     `json:"fias_code"`
+	FiasCode             string          `json:"fias_code"`
 	FiasLevel            string          `json:"fias_level"`
 	FiasActualityState   string          `json:"fias_actuality_state"`
 	KladrID              string          `json:"kladr_id"`
@@ -156,20 +316,51 @@ type Data struct {
 	Oktmo                string          `json:"oktmo"`
 	TaxOffice            string          `json:"tax_office"`
 	TaxOfficeLegal       string          `json:"tax_office_legal"`
-	Timezone             interface{}     `json:"timezone"`
+	Timezone             string          `json:"timezone"`
 	GeoLat               string          `json:"geo_lat"`
 	GeoLon               string          `json:"geo_lon"`
-	BeltwayHit           interface{}     `json:"beltway_hit"`
-	BeltwayDistance      interface{}     `json:"beltway_distance"`
-	Metro                interface{}     `json:"metro"`
-	Divisions            interface{}     `json:"divisions"`
-	QcGeo                string          `json:"qc_geo"`
-	QcComplete           interface{}     `json:"qc_complete"`
-	QcHouse              interface{}     `json:"qc_house"`
+	BeltwayHit           string          `json:"beltway_hit"`
+	BeltwayDistance      FlexString      `json:"beltway_distance"`
+	Metro                []Metro         `json:"metro"`
+	Divisions            json.RawMessage `json:"divisions"` // формат зависит от тарифа DaData
+	QcGeo                FlexString      `json:"qc_geo"`
+	QcComplete           FlexString      `json:"qc_complete"`
+	QcHouse              FlexString      `json:"qc_house"`
 	HistoryValues        []string        `json:"history_values"`
-	UnparsedParts        interface{}     `json:"unparsed_parts"`
-	Source               interface{}     `json:"source"`
-	Qc                   interface{}     `json:"qc"`
+	UnparsedParts        string          `json:"unparsed_parts"`
+	Source               string          `json:"source"`
+	Qc                   FlexString      `json:"qc"`
+}
+
+// FlexString - строковое поле, которое DaData в разных API присылает то строкой, то числом
+type FlexString string
+
+func (f *FlexString) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*f = FlexString(s)
+		return nil
+	}
+	if string(data) == "null" {
+		*f = ""
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*f = FlexString(n)
+	return nil
+}
+
+// Metro - ближайшая станция метро
+type Metro struct {
+	Name     string  `json:"name"`
+	Line     string  `json:"line"`
+	Distance float64 `json:"distance"`
 }
 
 type City string
@@ -0,0 +1,74 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+// Cache - хранилище ответов провайдера. ttl == 0 означает время жизни по умолчанию для бэкенда.
+type Cache interface {
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// StaleCache - бэкенд, умеющий отдавать просроченные значения в пределах окна устаревания
+type StaleCache interface {
+	Cache
+	GetStale(ctx context.Context, key string) (value interface{}, stale bool, found bool, err error)
+}
+
+// ExpiringCache - бэкенд, отдающий вместе со значением остаток его времени жизни;
+// ttl <= 0 - значение устарело, но ещё в окне устаревания
+type ExpiringCache interface {
+	GetTTL(ctx context.Context, key string) (value interface{}, ttl time.Duration, found bool, err error)
+}
+
+// CachePubSub - бэкенд, через который реплики рассылают друг другу сообщения.
+// Subscribe блокируется, пока не отменят ctx или не оборвётся подписка.
+type CachePubSub interface {
+	Publish(ctx context.Context, channel, message string) error
+	Subscribe(ctx context.Context, channel string, fn func(message string)) error
+}
+
+// CacheEntryInfo - запись кэша для административного API.
+// TTLSeconds отрицательный, если запись уже устарела.
+type CacheEntryInfo struct {
+	Key        string      `json:"key"`
+	Value      interface{} `json:"value"`
+	AgeSeconds float64     `json:"age_seconds"`
+	TTLSeconds float64     `json:"ttl_seconds"`
+	Stale      bool        `json:"stale"`
+}
+
+type CacheStats struct {
+	Entries     int   `json:"entries"`
+	Bytes       int64 `json:"bytes,omitempty"`
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	StaleHits   int64 `json:"stale_hits"` // отданные просроченные значения
+	Evictions   int64 `json:"evictions"`
+	Expirations int64 `json:"expirations"`
+}
+
+// CacheAdmin - операции для просмотра и очистки кэша во время работы
+type CacheAdmin interface {
+	Cache
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	Entry(ctx context.Context, key string) (CacheEntryInfo, bool, error)
+	// DeleteKey удаляет ключ и сообщает, был ли он
+	DeleteKey(ctx context.Context, key string) (bool, error)
+	DeletePrefix(ctx context.Context, prefix string) (int, error)
+	Flush(ctx context.Context) error
+	Stats(ctx context.Context) (CacheStats, error)
+}
+
+// TierStats - статистика одного уровня многоуровневого кэша
+type TierStats struct {
+	Name  string     `json:"name"`
+	Stats CacheStats `json:"stats"`
+}
+
+type TieredCacheStats interface {
+	TierStats(ctx context.Context) ([]TierStats, error)
+}
@@ -0,0 +1,92 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Coordinate - широта или долгота в градусах в запросах. В JSON это число; строка,
+// как у провайдеров ("55.7573"), тоже принимается.
+type Coordinate float64
+
+// ParseCoordinate разбирает координату в строковом виде провайдера. Пустая строка -
+// адрес без координат, это не ошибка. Диапазон не проверяется, для этого есть ValidatePoint.
+func ParseCoordinate(s string) (Coordinate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid coordinate %q", s)
+	}
+	return Coordinate(v), nil
+}
+
+func (c Coordinate) String() string {
+	return strconv.FormatFloat(float64(c), 'f', -1, 64)
+}
+
+func (c Coordinate) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(c)) || math.IsInf(float64(c), 0) {
+		return nil, fmt.Errorf("coordinate %v is not a finite number", float64(c))
+	}
+	return []byte(c.String()), nil
+}
+
+func (c *Coordinate) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+	}
+	v, err := ParseCoordinate(s)
+	if err != nil {
+		return err
+	}
+	*c = v
+	return nil
+}
+
+// unmarshalField разбирает обязательную координату поля запроса; ошибка - *FieldError с именем поля.
+// encoding/json не сообщает имя поля для ошибок из UnmarshalJSON, поэтому поля разбираются вручную.
+func (c *Coordinate) unmarshalField(data json.RawMessage, field string) error {
+	if data == nil || string(data) == "null" || string(data) == `""` {
+		return &FieldError{Field: field, Message: "is required"}
+	}
+	if err := c.UnmarshalJSON(data); err != nil {
+		return &FieldError{Field: field, Message: err.Error()}
+	}
+	return nil
+}
+
+// ValidatePoint проверяет, что координаты - конечные числа в допустимых пределах.
+// Ошибка - *FieldError с именем поля запроса.
+func ValidatePoint(lat, lng Coordinate, latField, lngField string) error {
+	if err := validateCoordinate(lat, 90); err != nil {
+		return &FieldError{Field: latField, Message: err.Error()}
+	}
+	if err := validateCoordinate(lng, 180); err != nil {
+		return &FieldError{Field: lngField, Message: err.Error()}
+	}
+	return nil
+}
+
+func validateCoordinate(c Coordinate, limit float64) error {
+	v := float64(c)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return errors.New("must be a finite number")
+	}
+	if v < -limit || v > limit {
+		return fmt.Errorf("must be between %v and %v, got %v", -limit, limit, v)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrCircuitOpen - провайдер временно отключён автоматом защиты, запрос не отправлялся
+var ErrCircuitOpen = errors.New("geo provider is temporarily unavailable: circuit breaker is open")
+
+// ErrRateLimited - клиентский ограничитель частоты не пропустил запрос к провайдеру
+var ErrRateLimited = errors.New("geo provider rate limit exceeded")
+
+// ErrQuotaExhausted - суточная квота провайдера исчерпана
+var ErrQuotaExhausted = errors.New("geo provider daily quota exhausted")
+
+// ErrStaleOnly - провайдер упёрся в лимит, а политика разрешает отвечать только из кэша.
+// Оборачивается вместе с ErrRateLimited или ErrQuotaExhausted.
+var ErrStaleOnly = errors.New("serving from cache only")
+
+// QuotaStatus - состояние лимитов одного провайдера
+type QuotaStatus struct {
+	Provider string  `json:"provider"`
+	Policy   string  `json:"policy"`
+	Rate     float64 `json:"rate"`
+	Burst    int     `json:"burst"`
+	// Daily == nil, если суточной квоты нет
+	Daily *DailyQuotaStatus `json:"daily,omitempty"`
+}
+
+type DailyQuotaStatus struct {
+	Limit     int       `json:"limit"`
+	Used      int       `json:"used"`
+	Remaining int       `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// QuotaReporter отдаёт состояние лимитов провайдеров
+type QuotaReporter interface {
+	Quotas() []QuotaStatus
+}
+
+// BreakerReporter отдаёт состояния автоматов защиты провайдера, ключ - "<провайдер>/<метод>"
+type BreakerReporter interface {
+	BreakerStates() map[string]string
+}
+
+// upstreamBodyLimit - сколько байт тела ответа провайдера сохранять в ошибке
+const upstreamBodyLimit = 256
+
+// UpstreamError - неуспешный HTTP-ответ геопровайдера
+type UpstreamError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func NewUpstreamError(provider string, statusCode int, body []byte) *UpstreamError {
+	if len(body) > upstreamBodyLimit {
+		body = body[:upstreamBodyLimit]
+	}
+	return &UpstreamError{Provider: provider, StatusCode: statusCode, Body: string(body)}
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s: unexpected status %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s: unexpected status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// Quota сообщает, что провайдер отказал из-за лимитов или исчерпанной квоты
+func (e *UpstreamError) Quota() bool {
+	switch e.StatusCode {
+	case http.StatusPaymentRequired, http.StatusForbidden, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// Temporary сообщает об ошибке на стороне провайдера (5xx)
+func (e *UpstreamError) Temporary() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// FieldError - некорректное значение поля запроса; клиенту отдаётся 400 с именем поля
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
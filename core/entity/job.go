@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrJobNotFound - задания нет или оно принадлежит другому пользователю
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobNotFinished - результаты запрошены до завершения задания
+var ErrJobNotFinished = errors.New("job is not finished yet")
+
+// JobKind - какой use case выполняет задание
+type JobKind string
+
+const (
+	JobSearch  JobKind = "search"
+	JobGeocode JobKind = "geocode"
+)
+
+// JobState - состояние задания
+type JobState string
+
+const (
+	JobQueued   JobState = "queued"
+	JobRunning  JobState = "running"
+	JobDone     JobState = "done"
+	JobFailed   JobState = "failed"
+	JobCanceled JobState = "canceled"
+)
+
+// Finished сообщает, что задание больше не будет выполняться
+func (s JobState) Finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCanceled
+}
+
+// Job - асинхронное задание геокодирования
+type Job struct {
+	ID    string   `json:"id"`
+	Kind  JobKind  `json:"kind"`
+	State JobState `json:"state"`
+	// Owner - user_id из токена (User.ID, а не имя); чужие задания не видны
+	Owner      string     `json:"-"`
+	Total      int        `json:"total"`
+	Processed  int        `json:"processed"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// JobItem - один запрос задания. Error заполняется, если строку входного файла не удалось разобрать.
+type JobItem struct {
+	Query string     `json:"query,omitempty"`
+	Lat   Coordinate `json:"lat,omitempty"`
+	Lng   Coordinate `json:"lng,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// JobResult - результат элемента задания; Index - номер элемента во входном файле
+type JobResult struct {
+	Index     int        `json:"index"`
+	Item      JobItem    `json:"item"`
+	Addresses []*Address `json:"addresses,omitempty"`
+	Provider  string     `json:"provider,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// JobStore хранит задания, их входные данные и результаты так, чтобы они пережили перезапуск
+type JobStore interface {
+	SaveJob(job Job) error
+	ListJobs() ([]Job, error)
+	SaveInput(id string, items []JobItem) error
+	LoadInput(id string) ([]JobItem, error)
+	// AppendResult дописывает результат; порядок результатов не совпадает с порядком элементов
+	AppendResult(id string, result JobResult) error
+	// EachResult передаёт результаты в fn по одному в порядке Index; если элемент записан
+	// несколько раз, передаётся последний результат. Ошибка fn прерывает обход.
+	EachResult(id string, fn func(JobResult) error) error
+	// DeleteJob удаляет задание вместе с входными данными и результатами
+	DeleteJob(id string) error
+}
@@ -11,7 +11,13 @@ type Responder interface {
 	ErrorUnauthorized(w http.ResponseWriter, err error)
 	ErrorBadRequest(w http.ResponseWriter, err error)
 	ErrorForbidden(w http.ResponseWriter, err error)
+	ErrorNotFound(w http.ResponseWriter, err error)
 	ErrorInternal(w http.ResponseWriter, err error)
+	ErrorServiceUnavailable(w http.ResponseWriter, err error)
+	ErrorGatewayTimeout(w http.ResponseWriter, err error)
+	ErrorConflict(w http.ResponseWriter, err error)
+	ErrorBadGateway(w http.ResponseWriter, err error)
+	ErrorTooManyRequests(w http.ResponseWriter, err error)
 }
 
 type LoginResponse struct {
@@ -22,9 +28,21 @@ var (
 	TokenAuth = jwtauth.New("HS256", []byte("your_secret_key"), nil)
 	Users     = make(map[string]User) // Хранение пользователей
 	Tokens    = make(map[string]struct{})
+	// AdminUsers - пользователи, получающие роль admin при входе; заполняется usecase.AddAdmin
+	AdminUsers = make(map[string]struct{})
+)
+
+const (
+	UserIDClaim = "user_id"
+	RoleClaim   = "role"
+	RoleAdmin   = "admin"
 )
 
 type User struct {
+	// ID - случайный идентификатор учётной записи, его выдаёт регистрация; в токене это user_id.
+	// Пользователи хранятся только в памяти, поэтому после перезапуска то же имя получает
+	// новый ID и не видит заданий прежнего владельца.
+	ID       string `json:"-"`
 	Username string `json:"username"`
 	Password string `json:"password"`
 }
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// BatchLimits ограничивает пакетные запросы: сколько элементов принимается за раз
+// и сколько из них обрабатывается одновременно
+type BatchLimits struct {
+	MaxItems    int
+	Concurrency int
+}
+
+var (
+	batchLimitsMu sync.RWMutex
+	batchLimits   = BatchLimits{MaxItems: 1000, Concurrency: 8}
+)
+
+func SetBatchLimits(l BatchLimits) {
+	batchLimitsMu.Lock()
+	defer batchLimitsMu.Unlock()
+	batchLimits = l
+}
+
+func currentBatchLimits() BatchLimits {
+	batchLimitsMu.RLock()
+	defer batchLimitsMu.RUnlock()
+	return batchLimits
+}
+
+// BatchResult - результат одного элемента пакета, в том же порядке, что и запросы
+type BatchResult struct {
+	Geo entity.ResponseAddresses
+	Err error
+}
+
+// HandleGeocodeBatch выполняет HandleGeocodeRequest для каждого элемента. Ошибка
+// элемента не прерывает пакет; ошибка всего пакета - только превышение BatchLimits.
+func HandleGeocodeBatch(ctx context.Context, reqs []entity.GeocodeRequest, geoService entity.GeoProvider, cache entity.Cache) ([]BatchResult, error) {
+	return runBatch(ctx, "geocode", len(reqs), func(ctx context.Context, i int) (entity.ResponseAddresses, error) {
+		return HandleGeocodeRequest(ctx, reqs[i], geoService, cache)
+	})
+}
+
+// HandleSearchBatch - пакетный HandleGeocodeAddressReq
+func HandleSearchBatch(ctx context.Context, reqs []entity.RequestAddressSearch, geoService entity.GeoProvider, cache entity.Cache) ([]BatchResult, error) {
+	return runBatch(ctx, "search", len(reqs), func(ctx context.Context, i int) (entity.ResponseAddresses, error) {
+		return HandleGeocodeAddressReq(ctx, reqs[i], geoService, cache)
+	})
+}
+
+// BatchTimeout - сколько может идти пакет из n элементов вида kind ("geocode" или "search"),
+// если каждый вызов провайдера упрётся в дедлайн из Timeouts. 0 - у вызовов нет дедлайна.
+func BatchTimeout(kind string, n int) time.Duration {
+	timeout := currentTimeouts().forKind(kind)
+	if timeout <= 0 || n <= 0 {
+		return 0
+	}
+	concurrency := max(currentBatchLimits().Concurrency, 1)
+	rounds := (n + concurrency - 1) / concurrency
+	return time.Duration(rounds) * timeout
+}
+
+// ValidateBatchSize проверяет размер пакета по BatchLimits; ошибка - *entity.FieldError
+func ValidateBatchSize(n int) error {
+	limits := currentBatchLimits()
+	if n == 0 {
+		return &entity.FieldError{Field: "items", Message: "must not be empty"}
+	}
+	if limits.MaxItems > 0 && n > limits.MaxItems {
+		return &entity.FieldError{Field: "items", Message: fmt.Sprintf("at most %d items per request, got %d", limits.MaxItems, n)}
+	}
+	return nil
+}
+
+// runBatch обрабатывает n элементов не более чем в Concurrency горутин. Одинаковые элементы
+// не ходят к провайдеру дважды: их объединяет кэш и geoFlight. После отмены ctx
+// необработанные элементы получают ctx.Err().
+func runBatch(ctx context.Context, kind string, n int, handle func(ctx context.Context, i int) (entity.ResponseAddresses, error)) ([]BatchResult, error) {
+	if err := ValidateBatchSize(n); err != nil {
+		return nil, err
+	}
+	limits := currentBatchLimits()
+	adapter.DefaultMetrics.Inc(kind + ".batches")
+	adapter.DefaultMetrics.Add(kind+".batch_items", int64(n))
+
+	results := make([]BatchResult, n)
+	sem := make(chan struct{}, max(limits.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i := range n {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			geo, err := handle(ctx, i)
+			results[i] = BatchResult{Geo: geo, Err: err}
+		}(i)
+	}
+	wg.Wait()
+	return results, nil
+}
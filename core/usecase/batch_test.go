@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// countingProvider отвечает адресом с городом из запроса и считает одновременные вызовы
+type countingProvider struct {
+	entity.GeoProvider
+	mu       sync.Mutex
+	calls    map[string]int
+	inFlight int32
+	peak     int32
+}
+
+func (p *countingProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	n := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&p.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&p.peak, peak, n) {
+			break
+		}
+	}
+	p.mu.Lock()
+	p.calls[query]++
+	p.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	if query == "fail" {
+		return entity.ResponseAddresses{}, errors.New("upstream failed")
+	}
+	return entity.ResponseAddresses{Addresses: []*entity.Address{{City: query}}}, nil
+}
+
+func TestHandleSearchBatch(t *testing.T) {
+	SetBatchLimits(BatchLimits{MaxItems: 10, Concurrency: 2})
+	defer SetBatchLimits(BatchLimits{MaxItems: 1000, Concurrency: 8})
+
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	provider := &countingProvider{calls: make(map[string]int)}
+	queries := []string{"a", "b", "fail", "a", "c", "d", "a"}
+	reqs := make([]entity.RequestAddressSearch, len(queries))
+	for i, q := range queries {
+		reqs[i] = entity.RequestAddressSearch{Query: q}
+	}
+
+	results, err := HandleSearchBatch(context.Background(), reqs, provider, adapter.NewMemoryCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, q := range queries {
+		if q == "fail" {
+			if results[i].Err == nil {
+				t.Errorf("item %d: expected error", i)
+			}
+			continue
+		}
+		if results[i].Err != nil || len(results[i].Geo.Addresses) != 1 || results[i].Geo.Addresses[0].City != q {
+			t.Errorf("item %d: unexpected result %+v", i, results[i])
+		}
+	}
+	if provider.calls["a"] != 1 {
+		t.Errorf("expected repeated query to reuse cache, got %d calls", provider.calls["a"])
+	}
+	if provider.peak > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", provider.peak)
+	}
+}
+
+func TestHandleSearchBatchLimits(t *testing.T) {
+	SetBatchLimits(BatchLimits{MaxItems: 2, Concurrency: 1})
+	defer SetBatchLimits(BatchLimits{MaxItems: 1000, Concurrency: 8})
+
+	var fieldErr *entity.FieldError
+	_, err := HandleSearchBatch(context.Background(), make([]entity.RequestAddressSearch, 3), nil, nil)
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "items" {
+		t.Errorf("expected items field error, got %v", err)
+	}
+	if _, err := HandleSearchBatch(context.Background(), nil, nil, nil); !errors.As(err, &fieldErr) {
+		t.Errorf("expected error for empty batch, got %v", err)
+	}
+}
+
+func TestHandleSearchBatchCanceled(t *testing.T) {
+	SetBatchLimits(BatchLimits{MaxItems: 10, Concurrency: 1})
+	defer SetBatchLimits(BatchLimits{MaxItems: 1000, Concurrency: 8})
+
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	reqs := []entity.RequestAddressSearch{{Query: "slow 1"}, {Query: "slow 2"}, {Query: "slow 3"}}
+	results, err := HandleSearchBatch(ctx, reqs, slowProvider{}, adapter.NewMemoryCache(cache))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("item %d: expected canceled, got %v", i, result.Err)
+		}
+	}
+}
+
+func TestBatchTimeout(t *testing.T) {
+	defer SetTimeouts(currentTimeouts())
+	defer SetBatchLimits(currentBatchLimits())
+	SetTimeouts(Timeouts{Geocode: 2 * time.Second})
+	SetBatchLimits(BatchLimits{MaxItems: 1000, Concurrency: 8})
+
+	if got := BatchTimeout("geocode", 17); got != 6*time.Second {
+		t.Errorf("expected 3 rounds of 2s, got %v", got)
+	}
+	if got := BatchTimeout("search", 17); got != 0 {
+		t.Errorf("expected no deadline without a search timeout, got %v", got)
+	}
+}
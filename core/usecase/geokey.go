@@ -0,0 +1,211 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+)
+
+// GeoKeyStrategy строит ключ кэша для координат обратного геокодирования.
+// Точки, попавшие в одну ячейку, получают один ключ и делят закэшированный ответ.
+type GeoKeyStrategy interface {
+	Key(lat, lng float64) string
+	// Name используется в метриках, например "precision:4" или "geohash:7"
+	Name() string
+}
+
+// PrecisionKey округляет координаты до Digits знаков после запятой.
+// 4 знака - ячейка около 11 метров, 3 знака - около 110 метров.
+type PrecisionKey struct {
+	Digits int
+}
+
+func (p PrecisionKey) Key(lat, lng float64) string {
+	return strconv.FormatFloat(lat, 'f', p.Digits, 64) + ":" + strconv.FormatFloat(lng, 'f', p.Digits, 64)
+}
+
+func (p PrecisionKey) Name() string {
+	return "precision:" + strconv.Itoa(p.Digits)
+}
+
+// GeohashKey снапит координаты к ячейке geohash длиной Precision символов.
+// 7 символов - ячейка около 150x150 метров, 8 - около 40x20 метров.
+type GeohashKey struct {
+	Precision int
+}
+
+func (g GeohashKey) Key(lat, lng float64) string {
+	return Geohash(lat, lng, g.Precision)
+}
+
+func (g GeohashKey) Name() string {
+	return "geohash:" + strconv.Itoa(g.Precision)
+}
+
+// PrecisionForRadius подбирает округление, при котором ячейка не больше radius метров
+func PrecisionForRadius(radius float64) PrecisionKey {
+	const metersPerDegree = 111320
+	if radius <= 0 {
+		return PrecisionKey{Digits: 6}
+	}
+	digits := int(math.Ceil(math.Log10(metersPerDegree / radius)))
+	if digits < 0 {
+		digits = 0
+	}
+	if digits > 8 {
+		digits = 8
+	}
+	return PrecisionKey{Digits: digits}
+}
+
+// ParseGeoKeyStrategy разбирает настройку вида "precision:4", "geohash:7" или "radius:50" (метры)
+func ParseGeoKeyStrategy(s string) (GeoKeyStrategy, error) {
+	kind, param, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid geo key strategy %q", s)
+	}
+	switch kind {
+	case "precision":
+		digits, err := strconv.Atoi(param)
+		if err != nil || digits < 0 || digits > 10 {
+			return nil, fmt.Errorf("invalid precision %q", param)
+		}
+		return PrecisionKey{Digits: digits}, nil
+	case "geohash":
+		precision, err := strconv.Atoi(param)
+		if err != nil || precision < 1 || precision > 12 {
+			return nil, fmt.Errorf("invalid geohash precision %q", param)
+		}
+		return GeohashKey{Precision: precision}, nil
+	case "radius":
+		radius, err := strconv.ParseFloat(param, 64)
+		if err != nil || radius <= 0 {
+			return nil, fmt.Errorf("invalid radius %q", param)
+		}
+		return PrecisionForRadius(radius), nil
+	}
+	return nil, fmt.Errorf("unknown geo key strategy %q", kind)
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash кодирует координаты в строку geohash заданной длины
+func Geohash(lat, lng float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lngRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	even := true
+	bit, ch := 0, 0
+	for len(hash) < precision {
+		if even {
+			mid := (lngRange[0] + lngRange[1]) / 2
+			if lng >= mid {
+				ch |= 1 << (4 - bit)
+				lngRange[0] = mid
+			} else {
+				lngRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if lat >= mid {
+				ch |= 1 << (4 - bit)
+				latRange[0] = mid
+			} else {
+				latRange[1] = mid
+			}
+		}
+		even = !even
+		if bit < 4 {
+			bit++
+			continue
+		}
+		hash = append(hash, geohashAlphabet[ch])
+		bit, ch = 0, 0
+	}
+	return string(hash)
+}
+
+var (
+	geoKeyMu sync.RWMutex
+	// PrecisionKey{6} совпадает с прежним форматом ключа "%f:%f"
+	geoKey GeoKeyStrategy = PrecisionKey{Digits: 6}
+)
+
+// ConfigureGeoKeyStrategy применяет настройку вида ParseGeoKeyStrategy; пустая строка оставляет
+// стратегию по умолчанию. Сервер и прогрев кэша должны получать одну настройку, иначе
+// прогретые ответы лягут под ключи, которые сервер не ищет.
+func ConfigureGeoKeyStrategy(s string) error {
+	if s == "" {
+		return nil
+	}
+	strategy, err := ParseGeoKeyStrategy(s)
+	if err != nil {
+		return err
+	}
+	SetGeoKeyStrategy(strategy)
+	return nil
+}
+
+// SetGeoKeyStrategy меняет стратегию ключей для HandleGeocodeRequest
+func SetGeoKeyStrategy(s GeoKeyStrategy) {
+	geoKeyMu.Lock()
+	defer geoKeyMu.Unlock()
+	geoKey = s
+}
+
+func currentGeoKey() GeoKeyStrategy {
+	geoKeyMu.RLock()
+	defer geoKeyMu.RUnlock()
+	return geoKey
+}
+
+// GeoKeyStat - попадания в кэш для одной стратегии ключей
+type GeoKeyStat struct {
+	Strategy string  `json:"strategy"`
+	Hits     int64   `json:"hits"`
+	Misses   int64   `json:"misses"`
+	HitRate  float64 `json:"hit_rate"`
+}
+
+// GeoKeyStats возвращает hit rate по каждой стратегии, которая использовалась с момента запуска
+func GeoKeyStats() []GeoKeyStat {
+	counters := adapter.DefaultMetrics.Snapshot()
+	byName := make(map[string]*GeoKeyStat)
+	for name, value := range counters {
+		rest, ok := strings.CutPrefix(name, "geokey.")
+		if !ok {
+			continue
+		}
+		dot := strings.LastIndex(rest, ".")
+		if dot < 0 {
+			continue
+		}
+		strategy := rest[:dot]
+		stat, ok := byName[strategy]
+		if !ok {
+			stat = &GeoKeyStat{Strategy: strategy}
+			byName[strategy] = stat
+		}
+		switch rest[dot+1:] {
+		case "hits":
+			stat.Hits = value
+		case "misses":
+			stat.Misses = value
+		}
+	}
+
+	stats := make([]GeoKeyStat, 0, len(byName))
+	for _, stat := range byName {
+		if total := stat.Hits + stat.Misses; total > 0 {
+			stat.HitRate = float64(stat.Hits) / float64(total)
+		}
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Strategy < stats[j].Strategy })
+	return stats
+}
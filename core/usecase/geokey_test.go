@@ -0,0 +1,37 @@
+package usecase
+
+import "testing"
+
+func TestGeohash(t *testing.T) {
+	if got := Geohash(57.64911, 10.40744, 11); got != "u4pruydqqvj" {
+		t.Errorf("expected u4pruydqqvj, got %s", got)
+	}
+}
+
+func TestGeoKeySnapping(t *testing.T) {
+	// Две точки примерно в 10 метрах друг от друга на Красной площади
+	lat1, lng1 := 55.75393, 37.62079
+	lat2, lng2 := 55.75401, 37.62085
+
+	for _, strategy := range []GeoKeyStrategy{PrecisionKey{Digits: 3}, GeohashKey{Precision: 7}} {
+		if strategy.Key(lat1, lng1) != strategy.Key(lat2, lng2) {
+			t.Errorf("%s: expected nearby points to share a key", strategy.Name())
+		}
+	}
+	if exact := (PrecisionKey{Digits: 6}); exact.Key(lat1, lng1) == exact.Key(lat2, lng2) {
+		t.Error("precision:6 should distinguish points 10 meters apart")
+	}
+}
+
+func TestParseGeoKeyStrategy(t *testing.T) {
+	strategy, err := ParseGeoKeyStrategy("radius:100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy.Name() != "precision:4" {
+		t.Errorf("expected precision:4 for 100m, got %s", strategy.Name())
+	}
+	if _, err := ParseGeoKeyStrategy("geohash:0"); err == nil {
+		t.Error("expected error for geohash:0")
+	}
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// Форматы входных файлов и результатов заданий
+const (
+	JobFormatCSV    = "csv"
+	JobFormatNDJSON = "ndjson"
+)
+
+// ParseJobInput читает входной файл задания. CSV - с заголовком: для поиска нужна колонка
+// query (или address), для геокодирования - lat и lng (или lon). NDJSON - по объекту
+// {"query": ...} или {"lat": ..., "lng": ...} на строку. Строка, которую не удалось разобрать,
+// не отклоняет файл, а становится элементом с ошибкой.
+func ParseJobInput(r io.Reader, format string, kind entity.JobKind) ([]entity.JobItem, error) {
+	switch format {
+	case JobFormatCSV:
+		return parseJobCSV(r, kind)
+	case JobFormatNDJSON:
+		return parseJobNDJSON(r, kind)
+	}
+	return nil, &entity.FieldError{Field: "format", Message: fmt.Sprintf("expected %q or %q", JobFormatCSV, JobFormatNDJSON)}
+}
+
+func parseJobCSV(r io.Reader, kind entity.JobKind) ([]entity.JobItem, error) {
+	br := bufio.NewReader(r)
+	reader := csv.NewReader(br)
+	// Разделитель угадываем по заголовку: Excel в русской локали сохраняет CSV через ";"
+	head, _ := br.Peek(4096)
+	if head = firstLine(head); bytes.Count(head, []byte(";")) > bytes.Count(head, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv header: %w", err)
+	}
+	column := func(names ...string) int {
+		for i, h := range header {
+			h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+			for _, name := range names {
+				if h == name {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	queryCol := column("query", "address")
+	latCol, lngCol := column("lat", "latitude", "geo_lat"), column("lng", "lon", "longitude", "geo_lon")
+	if kind == entity.JobGeocode && (latCol < 0 || lngCol < 0) {
+		return nil, &entity.FieldError{Field: "file", Message: "csv: lat and lng columns are required"}
+	}
+	if kind != entity.JobGeocode && queryCol < 0 {
+		return nil, &entity.FieldError{Field: "file", Message: "csv: query column is required"}
+	}
+
+	var items []entity.JobItem
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(i int) string {
+			if i < 0 || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if kind != entity.JobGeocode {
+			// Пустой запрос не отправляется провайдеру и не тратит квоту
+			item := entity.JobItem{Query: field(queryCol)}
+			if item.Query == "" {
+				item.Error = fmt.Sprintf("line %d: query is required", line)
+			}
+			items = append(items, item)
+			continue
+		}
+		var item entity.JobItem
+		if field(latCol) == "" || field(lngCol) == "" {
+			item.Error = fmt.Sprintf("line %d: lat and lng are required", line)
+		} else if item.Lat, err = entity.ParseCoordinate(field(latCol)); err != nil {
+			item.Error = fmt.Sprintf("line %d: lat: %v", line, err)
+		} else if item.Lng, err = entity.ParseCoordinate(field(lngCol)); err != nil {
+			item.Error = fmt.Sprintf("line %d: lng: %v", line, err)
+		} else if err = entity.ValidatePoint(item.Lat, item.Lng, "lat", "lng"); err != nil {
+			item.Error = fmt.Sprintf("line %d: %v", line, err)
+		}
+		items = append(items, item)
+	}
+}
+
+func firstLine(data []byte) []byte {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i]
+	}
+	return data
+}
+
+func parseJobNDJSON(r io.Reader, kind entity.JobKind) ([]entity.JobItem, error) {
+	var items []entity.JobItem
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		// Указатели отличают отсутствующую координату от нулевой
+		var v struct {
+			Query string             `json:"query"`
+			Lat   *entity.Coordinate `json:"lat"`
+			Lng   *entity.Coordinate `json:"lng"`
+			Lon   *entity.Coordinate `json:"lon"`
+		}
+		if err := json.Unmarshal(text, &v); err != nil {
+			items = append(items, entity.JobItem{Error: fmt.Sprintf("line %d: %v", line, err)})
+			continue
+		}
+		if v.Lng == nil {
+			v.Lng = v.Lon
+		}
+		item := entity.JobItem{Query: v.Query}
+		if v.Lat != nil {
+			item.Lat = *v.Lat
+		}
+		if v.Lng != nil {
+			item.Lng = *v.Lng
+		}
+		switch {
+		case kind != entity.JobGeocode:
+			if strings.TrimSpace(item.Query) == "" {
+				item.Error = fmt.Sprintf("line %d: query is required", line)
+			}
+		case v.Lat == nil || v.Lng == nil:
+			item.Error = fmt.Sprintf("line %d: lat and lng are required", line)
+		default:
+			if err := entity.ValidatePoint(item.Lat, item.Lng, "lat", "lng"); err != nil {
+				item.Error = fmt.Sprintf("line %d: %v", line, err)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, scanner.Err()
+}
+
+// WriteJobResults выводит результаты задания из each (см. JobManager.Results) по мере чтения.
+// В NDJSON - entity.JobResult с адресами подробности detail, в CSV - по строке на элемент
+// с первым найденным адресом.
+func WriteJobResults(w io.Writer, format string, job entity.Job, detail entity.DetailLevel, each func(fn func(entity.JobResult) error) error) error {
+	if format == JobFormatNDJSON {
+		enc := json.NewEncoder(w)
+		return each(func(result entity.JobResult) error {
+			result.Addresses = entity.ResponseAddresses{Addresses: result.Addresses}.WithDetail(detail).Addresses
+			return enc.Encode(result)
+		})
+	}
+
+	cw := csv.NewWriter(w)
+	header := []string{"index", "query"}
+	if job.Kind == entity.JobGeocode {
+		header = []string{"index", "lat", "lng"}
+	}
+	header = append(header, "status", "error", "matches", "city", "street", "house", "geo_lat", "geo_lon", "provider")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	err := each(func(result entity.JobResult) error {
+		row := []string{strconv.Itoa(result.Index), result.Item.Query}
+		if job.Kind == entity.JobGeocode {
+			row = []string{strconv.Itoa(result.Index), result.Item.Lat.String(), result.Item.Lng.String()}
+		}
+		status := "ok"
+		if result.Error != "" {
+			status = "error"
+		}
+		row = append(row, status, result.Error, strconv.Itoa(len(result.Addresses)))
+		if len(result.Addresses) > 0 {
+			a := result.Addresses[0]
+			lat, lon := a.Coordinates()
+			row = append(row, a.City, a.Street, a.House, lat, lon, result.Provider)
+		} else {
+			row = append(row, "", "", "", "", "", result.Provider)
+		}
+		return cw.Write(row)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
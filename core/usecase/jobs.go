@@ -0,0 +1,499 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+type JobOptions struct {
+	// Workers - сколько элементов задания выполняется одновременно
+	Workers int
+	// Rate - не больше Rate элементов в секунду на все задания, 0 - без ограничения.
+	// Лимиты самого провайдера соблюдает цепочка провайдеров, Rate оставляет запас для онлайн-запросов.
+	Rate float64
+	// MaxItems - наибольшее количество элементов в задании
+	MaxItems int
+	// RateLimitRetries - сколько раз повторить элемент, отклонённый ограничителем частоты
+	RateLimitRetries int
+	// Concurrent - сколько заданий выполняется одновременно
+	Concurrent int
+	// Retention - сколько хранить завершённое задание и его файлы, 0 - бессрочно
+	Retention time.Duration
+}
+
+var DefaultJobOptions = JobOptions{Workers: 4, MaxItems: 1_000_000, RateLimitRetries: 5, Concurrent: 2, Retention: 7 * 24 * time.Hour}
+
+// jobSaveInterval - как часто сохранять прогресс выполняющегося задания
+const jobSaveInterval = time.Second
+
+// jobCollectInterval - как часто удалять задания старше Retention
+const jobCollectInterval = 10 * time.Minute
+
+// JobManager выполняет асинхронные задания, не больше Concurrent одновременно, элементы
+// задания - в Workers горутин. Освободившееся место получает владелец с наименьшим числом
+// выполняющихся заданий, а из равных - дольше всех ждавший, так что один пользователь
+// не займёт очередь. Состояние хранится в entity.JobStore: после перезапуска
+// незавершённые задания продолжаются с элементов, для которых ещё нет результата.
+type JobManager struct {
+	store      entity.JobStore
+	geoService entity.GeoProvider
+	cache      entity.Cache
+	opts       JobOptions
+	limiter    *adapter.TokenBucket
+
+	mu      sync.Mutex
+	jobs    map[string]*entity.Job
+	queue   []string
+	running map[string]context.CancelFunc
+	// starts и lastStart - номер последнего запуска задания каждого владельца
+	starts    uint64
+	lastStart map[string]uint64
+	wake      chan struct{}
+
+	ctx  context.Context
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+func NewJobManager(store entity.JobStore, geoService entity.GeoProvider, cache entity.Cache, opts JobOptions) (*JobManager, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Concurrent <= 0 {
+		opts.Concurrent = 1
+	}
+	jobs, err := store.ListJobs()
+	if err != nil {
+		return nil, err
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	m := &JobManager{
+		store:      store,
+		geoService: geoService,
+		cache:      cache,
+		opts:       opts,
+		jobs:       make(map[string]*entity.Job, len(jobs)),
+		running:    make(map[string]context.CancelFunc),
+		lastStart:  make(map[string]uint64),
+		wake:       make(chan struct{}, 1),
+		ctx:        ctx,
+		stop:       stop,
+		done:       make(chan struct{}),
+	}
+	if opts.Rate > 0 {
+		m.limiter = adapter.NewTokenBucket(opts.Rate, max(1, int(opts.Rate)))
+	}
+	for i := range jobs {
+		job := jobs[i]
+		if !job.State.Finished() {
+			// Задание, прерванное перезапуском, снова встаёт в очередь
+			job.State = entity.JobQueued
+			m.queue = append(m.queue, job.ID)
+		}
+		m.jobs[job.ID] = &job
+	}
+	m.collect(time.Now())
+	go m.dispatch()
+	return m, nil
+}
+
+// Close останавливает выполнение. Прерванное задание остаётся незавершённым
+// и продолжится при следующем запуске.
+func (m *JobManager) Close() {
+	m.stop()
+	<-m.done
+}
+
+// Submit ставит задание в очередь. Owner - владелец, только ему задание видно.
+func (m *JobManager) Submit(owner string, kind entity.JobKind, items []entity.JobItem) (entity.Job, error) {
+	if kind != entity.JobSearch && kind != entity.JobGeocode {
+		return entity.Job{}, &entity.FieldError{Field: "kind", Message: fmt.Sprintf("expected %q or %q", entity.JobSearch, entity.JobGeocode)}
+	}
+	if len(items) == 0 {
+		return entity.Job{}, &entity.FieldError{Field: "items", Message: "must not be empty"}
+	}
+	if m.opts.MaxItems > 0 && len(items) > m.opts.MaxItems {
+		return entity.Job{}, &entity.FieldError{Field: "items", Message: fmt.Sprintf("at most %d items per job, got %d", m.opts.MaxItems, len(items))}
+	}
+	id, err := newJobID()
+	if err != nil {
+		return entity.Job{}, err
+	}
+	job := entity.Job{
+		ID:        id,
+		Kind:      kind,
+		State:     entity.JobQueued,
+		Owner:     owner,
+		Total:     len(items),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := m.store.SaveInput(job.ID, items); err != nil {
+		return entity.Job{}, err
+	}
+	if err := m.store.SaveJob(job); err != nil {
+		return entity.Job{}, err
+	}
+
+	// В карту кладём копию: её меняет run, а job возвращается вызывающему
+	stored := job
+	m.mu.Lock()
+	m.jobs[job.ID] = &stored
+	m.queue = append(m.queue, job.ID)
+	m.mu.Unlock()
+	adapter.DefaultMetrics.Inc("jobs.submitted")
+	m.signal()
+	return job, nil
+}
+
+// signal будит dispatch: в очереди новое задание или освободилось место
+func (m *JobManager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *JobManager) Get(owner, id string) (entity.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || job.Owner != owner {
+		return entity.Job{}, entity.ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// List возвращает задания владельца, старые первыми
+func (m *JobManager) List(owner string) []entity.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []entity.Job
+	for _, job := range m.jobs {
+		if job.Owner == owner {
+			res = append(res, *job)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res
+}
+
+// Cancel отменяет задание. Уже полученные результаты сохраняются и доступны для скачивания.
+// Отмена завершённого задания ничего не делает.
+func (m *JobManager) Cancel(owner, id string) (entity.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok || job.Owner != owner {
+		return entity.Job{}, entity.ErrJobNotFound
+	}
+	switch {
+	case job.State == entity.JobQueued:
+		m.finish(job, entity.JobCanceled, "")
+	case job.State == entity.JobRunning:
+		// Состояние выставит run, когда остановятся исполнители. Без записи в running
+		// задание уже остановлено при выключении сервера и ждёт перезапуска.
+		if cancel, ok := m.running[id]; ok {
+			cancel()
+		} else {
+			m.finish(job, entity.JobCanceled, "")
+		}
+	}
+	return *job, nil
+}
+
+// Results проверяет, что задание завершено, и возвращает его вместе с обходом результатов
+// в порядке входного файла. Результаты читаются из хранилища по одному, а не загружаются в память.
+func (m *JobManager) Results(owner, id string) (entity.Job, func(fn func(entity.JobResult) error) error, error) {
+	job, err := m.Get(owner, id)
+	if err != nil {
+		return entity.Job{}, nil, err
+	}
+	if !job.State.Finished() {
+		return job, nil, entity.ErrJobNotFinished
+	}
+	each := func(fn func(entity.JobResult) error) error {
+		return m.store.EachResult(id, fn)
+	}
+	return job, each, nil
+}
+
+func (m *JobManager) dispatch() {
+	defer close(m.done)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ticker := time.NewTicker(jobCollectInterval)
+	defer ticker.Stop()
+	for {
+		m.mu.Lock()
+		job, ctx, cancel := m.next()
+		m.mu.Unlock()
+		if job == nil {
+			select {
+			case <-m.wake:
+			case now := <-ticker.C:
+				m.collect(now)
+			case <-m.ctx.Done():
+				return
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.run(ctx, cancel, job)
+			m.signal()
+		}()
+	}
+}
+
+// next выбирает следующее задание, если есть свободное место, и переводит его в JobRunning.
+// Задания, отменённые в очереди, выбрасываются. Вызывается под mu.
+func (m *JobManager) next() (*entity.Job, context.Context, context.CancelFunc) {
+	if len(m.running) >= m.opts.Concurrent || m.ctx.Err() != nil {
+		return nil, nil, nil
+	}
+	perOwner := make(map[string]int, len(m.running))
+	for id := range m.running {
+		perOwner[m.jobs[id].Owner]++
+	}
+	best := -1
+	queue := m.queue[:0]
+	for _, id := range m.queue {
+		job := m.jobs[id]
+		if job == nil || job.State != entity.JobQueued {
+			continue
+		}
+		queue = append(queue, id)
+		if best < 0 {
+			best = len(queue) - 1
+			continue
+		}
+		current := m.jobs[queue[best]]
+		if running, bestRunning := perOwner[job.Owner], perOwner[current.Owner]; running < bestRunning ||
+			(running == bestRunning && m.lastStart[job.Owner] < m.lastStart[current.Owner]) {
+			best = len(queue) - 1
+		}
+	}
+	m.queue = queue
+	if best < 0 {
+		return nil, nil, nil
+	}
+	job := m.jobs[m.queue[best]]
+	m.queue = append(m.queue[:best], m.queue[best+1:]...)
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.running[job.ID] = cancel
+	m.starts++
+	m.lastStart[job.Owner] = m.starts
+	job.State = entity.JobRunning
+	if job.StartedAt == nil {
+		now := time.Now().UTC()
+		job.StartedAt = &now
+	}
+	return job, ctx, cancel
+}
+
+// collect удаляет завершённые задания старше Retention вместе с их файлами
+func (m *JobManager) collect(now time.Time) {
+	if m.opts.Retention <= 0 {
+		return
+	}
+	m.mu.Lock()
+	var expired []string
+	for id, job := range m.jobs {
+		if job.State.Finished() && job.FinishedAt != nil && now.Sub(*job.FinishedAt) > m.opts.Retention {
+			expired = append(expired, id)
+			delete(m.jobs, id)
+		}
+	}
+	m.mu.Unlock()
+	for _, id := range expired {
+		if err := m.store.DeleteJob(id); err != nil {
+			adapter.DefaultMetrics.Inc("jobs.delete_errors")
+			continue
+		}
+		adapter.DefaultMetrics.Inc("jobs.expired")
+	}
+}
+
+// run выполняет задание, которое next перевёл в JobRunning
+func (m *JobManager) run(ctx context.Context, cancel context.CancelFunc, job *entity.Job) {
+	defer cancel()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, job.ID)
+		m.mu.Unlock()
+	}()
+
+	items, err := m.store.LoadInput(job.ID)
+	if err != nil {
+		m.fail(job, err)
+		return
+	}
+	completed := make(map[int]bool)
+	failed := 0
+	err = m.store.EachResult(job.ID, func(result entity.JobResult) error {
+		completed[result.Index] = true
+		if result.Error != "" {
+			failed++
+		}
+		return nil
+	})
+	if err != nil {
+		m.fail(job, err)
+		return
+	}
+	m.mu.Lock()
+	job.Processed, job.Failed = len(completed), failed
+	m.save(job)
+	m.mu.Unlock()
+
+	pending := make(chan int)
+	var storeErr error
+	var wg sync.WaitGroup
+	lastSave := time.Now()
+	for w := 0; w < m.opts.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				result, ok := m.process(ctx, job.Kind, i, items[i])
+				if !ok {
+					continue
+				}
+				err := m.store.AppendResult(job.ID, result)
+
+				m.mu.Lock()
+				if err != nil {
+					if storeErr == nil {
+						storeErr = err
+						cancel()
+					}
+					m.mu.Unlock()
+					continue
+				}
+				job.Processed++
+				if result.Error != "" {
+					job.Failed++
+				}
+				if time.Since(lastSave) >= jobSaveInterval {
+					lastSave = time.Now()
+					m.save(job)
+				}
+				m.mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for i := range items {
+		if completed[i] {
+			continue
+		}
+		select {
+		case pending <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(pending)
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case storeErr != nil:
+		m.finish(job, entity.JobFailed, storeErr.Error())
+	case m.ctx.Err() != nil:
+		// Остановка сервера: задание продолжится после перезапуска
+		m.save(job)
+	case ctx.Err() != nil:
+		m.finish(job, entity.JobCanceled, "")
+	default:
+		m.finish(job, entity.JobDone, "")
+	}
+}
+
+// process выполняет один элемент. ok == false - элемент прерван отменой задания,
+// его результат не сохраняется, чтобы после перезапуска он выполнился снова.
+func (m *JobManager) process(ctx context.Context, kind entity.JobKind, index int, item entity.JobItem) (result entity.JobResult, ok bool) {
+	result = entity.JobResult{Index: index, Item: item}
+	if item.Error != "" {
+		result.Error = item.Error
+		return result, true
+	}
+	for attempt := 0; ; attempt++ {
+		if m.limiter != nil {
+			if err := m.limiter.Wait(ctx); err != nil {
+				return result, false
+			}
+		}
+		var geo entity.ResponseAddresses
+		var err error
+		switch kind {
+		case entity.JobGeocode:
+			geo, err = HandleGeocodeRequest(ctx, entity.GeocodeRequest{Lat: item.Lat, Lng: item.Lng}, m.geoService, m.cache)
+		default:
+			geo, err = HandleGeocodeAddressReq(ctx, entity.RequestAddressSearch{Query: item.Query}, m.geoService, m.cache)
+		}
+		if ctx.Err() != nil {
+			return result, false
+		}
+		if errors.Is(err, entity.ErrRateLimited) && attempt < m.opts.RateLimitRetries {
+			adapter.DefaultMetrics.Inc("jobs.rate_limited")
+			select {
+			case <-time.After(time.Duration(attempt+1) * time.Second):
+				continue
+			case <-ctx.Done():
+				return result, false
+			}
+		}
+		if err != nil {
+			result.Error = err.Error()
+			return result, true
+		}
+		result.Addresses, result.Provider = geo.Addresses, geo.Provider
+		return result, true
+	}
+}
+
+// fail завершает задание с ошибкой хранилища
+func (m *JobManager) fail(job *entity.Job, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finish(job, entity.JobFailed, err.Error())
+}
+
+// finish переводит задание в конечное состояние. Вызывается под mu.
+func (m *JobManager) finish(job *entity.Job, state entity.JobState, message string) {
+	now := time.Now().UTC()
+	job.State, job.Error, job.FinishedAt = state, message, &now
+	adapter.DefaultMetrics.Inc("jobs." + string(state))
+	m.save(job)
+}
+
+// save сохраняет состояние задания. Вызывается под mu; ошибка только логируется в метриках:
+// прогресс восстановится из файла результатов.
+func (m *JobManager) save(job *entity.Job) {
+	if err := m.store.SaveJob(*job); err != nil {
+		adapter.DefaultMetrics.Inc("jobs.save_errors")
+	}
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("job id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,308 @@
+package usecase
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func newTestJobManager(t *testing.T, store entity.JobStore, provider entity.GeoProvider) *JobManager {
+	t.Helper()
+	opts := DefaultJobOptions
+	opts.Workers = 2
+	opts.Concurrent = 1
+	return newTestJobManagerOpts(t, store, provider, opts)
+}
+
+func newTestJobManagerOpts(t *testing.T, store entity.JobStore, provider entity.GeoProvider, opts JobOptions) *JobManager {
+	t.Helper()
+	cache := adapter.NewCache(time.Minute)
+	t.Cleanup(cache.Close)
+	m, err := NewJobManager(store, provider, adapter.NewMemoryCache(cache), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+// waitJob ждёт, пока задание завершится
+func waitJob(t *testing.T, m *JobManager, owner, id string) entity.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(owner, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State.Finished() {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job is still %s", job.State)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitJobState ждёт, пока задание перейдёт в состояние state
+func waitJobState(t *testing.T, m *JobManager, owner, id string, state entity.JobState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(owner, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job is still %s, expected %s", job.State, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// jobResults собирает результаты завершённого задания
+func jobResults(t *testing.T, m *JobManager, owner, id string) []entity.JobResult {
+	t.Helper()
+	_, each, err := m.Results(owner, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []entity.JobResult
+	if err := each(func(result entity.JobResult) error {
+		results = append(results, result)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestJobManagerRun(t *testing.T) {
+	store, _ := adapter.NewFileJobStore(t.TempDir())
+	provider := &countingProvider{calls: make(map[string]int)}
+	m := newTestJobManager(t, store, provider)
+
+	items := []entity.JobItem{{Query: "job-a"}, {Query: "fail"}, {Error: "line 4: bad"}, {Query: "job-b"}}
+	job, err := m.Submit("alice", entity.JobSearch, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Get("bob", job.ID); err != entity.ErrJobNotFound {
+		t.Errorf("expected other owners not to see the job, got %v", err)
+	}
+
+	job = waitJob(t, m, "alice", job.ID)
+	if job.State != entity.JobDone || job.Processed != 4 || job.Failed != 2 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	results := jobResults(t, m, "alice", job.ID)
+	if len(results) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(results))
+	}
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("expected results in input order, got index %d at %d", result.Index, i)
+		}
+	}
+	if results[0].Addresses[0].City != "job-a" || results[1].Error == "" || results[2].Error != "line 4: bad" {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if len(m.List("alice")) != 1 || len(m.List("bob")) != 0 {
+		t.Error("expected jobs to be listed per owner")
+	}
+}
+
+func TestJobManagerCancel(t *testing.T) {
+	store, _ := adapter.NewFileJobStore(t.TempDir())
+	m := newTestJobManager(t, store, slowProvider{})
+
+	running, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "slow-1"}, {Query: "slow-2"}})
+	queued, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "slow-3"}})
+	if _, _, err := m.Results("alice", running.ID); err != entity.ErrJobNotFinished {
+		t.Errorf("expected ErrJobNotFinished, got %v", err)
+	}
+
+	if job, _ := m.Cancel("alice", queued.ID); job.State != entity.JobCanceled {
+		t.Errorf("expected a queued job to be canceled at once, got %s", job.State)
+	}
+	m.Cancel("alice", running.ID)
+	if job := waitJob(t, m, "alice", running.ID); job.State != entity.JobCanceled || job.Processed != 0 {
+		t.Errorf("unexpected job %+v", job)
+	}
+}
+
+func TestJobManagerCancelAfterClose(t *testing.T) {
+	store, _ := adapter.NewFileJobStore(t.TempDir())
+	m := newTestJobManager(t, store, slowProvider{})
+	job, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "close-cancel"}})
+	waitJobState(t, m, "alice", job.ID, entity.JobRunning)
+	// После остановки задание остаётся JobRunning, но исполнителя у него уже нет
+	m.Close()
+
+	job, err := m.Cancel("alice", job.ID)
+	if err != nil || job.State != entity.JobCanceled {
+		t.Errorf("expected the stopped job to be canceled, got %s, %v", job.State, err)
+	}
+}
+
+func TestJobManagerResume(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := adapter.NewFileJobStore(dir)
+	m := newTestJobManager(t, store, slowProvider{})
+	job, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "resume-a"}, {Query: "resume-b"}, {Query: "resume-c"}})
+	// Первый элемент успел выполниться до остановки
+	store.AppendResult(job.ID, entity.JobResult{Index: 0, Item: entity.JobItem{Query: "resume-a"}, Provider: "before"})
+	time.Sleep(20 * time.Millisecond)
+	m.Close()
+
+	if saved, _ := store.ListJobs(); saved[0].State.Finished() {
+		t.Fatalf("expected the interrupted job to stay unfinished, got %s", saved[0].State)
+	}
+
+	provider := &countingProvider{calls: make(map[string]int)}
+	restarted := newTestJobManager(t, store, provider)
+	job = waitJob(t, restarted, "alice", job.ID)
+	if job.State != entity.JobDone || job.Processed != 3 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if provider.calls["resume-a"] != 0 || provider.calls["resume-b"] != 1 || provider.calls["resume-c"] != 1 {
+		t.Errorf("expected only unfinished items to run again, got %v", provider.calls)
+	}
+	results := jobResults(t, restarted, "alice", job.ID)
+	if len(results) != 3 || results[0].Provider != "before" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestJobManagerFairness(t *testing.T) {
+	store, _ := adapter.NewFileJobStore(t.TempDir())
+	opts := DefaultJobOptions
+	opts.Concurrent = 2
+	m := newTestJobManagerOpts(t, store, slowProvider{}, opts)
+
+	alice1, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "fair-a1"}})
+	waitJobState(t, m, "alice", alice1.ID, entity.JobRunning)
+	alice2, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "fair-a2"}})
+	alice3, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "fair-a3"}})
+	bob, _ := m.Submit("bob", entity.JobSearch, []entity.JobItem{{Query: "fair-b"}})
+
+	// Второе место достаётся не следующему заданию alice, а владельцу без выполняющихся заданий.
+	// alice2 может успеть занять его до Submit от bob, тогда bob получит место после alice1.
+	if job, _ := m.Get("alice", alice2.ID); job.State == entity.JobRunning {
+		m.Cancel("alice", alice1.ID)
+	}
+	waitJobState(t, m, "bob", bob.ID, entity.JobRunning)
+	if job, _ := m.Get("alice", alice3.ID); job.State != entity.JobQueued {
+		t.Errorf("expected alice's third job to wait for bob, got %s", job.State)
+	}
+}
+
+func TestJobManagerRetention(t *testing.T) {
+	dir := t.TempDir()
+	store, _ := adapter.NewFileJobStore(dir)
+	provider := &countingProvider{calls: make(map[string]int)}
+	opts := DefaultJobOptions
+	opts.Retention = time.Hour
+	m := newTestJobManagerOpts(t, store, provider, opts)
+
+	done, _ := m.Submit("alice", entity.JobSearch, []entity.JobItem{{Query: "retention-a"}})
+	done = waitJob(t, m, "alice", done.ID)
+
+	m.collect(done.FinishedAt.Add(time.Minute))
+	if _, err := m.Get("alice", done.ID); err != nil {
+		t.Fatalf("expected a fresh job to be kept, got %v", err)
+	}
+	m.collect(done.FinishedAt.Add(2 * time.Hour))
+	if _, err := m.Get("alice", done.ID); err != entity.ErrJobNotFound {
+		t.Errorf("expected an expired job to be removed, got %v", err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected job files to be deleted, got %v", files)
+	}
+}
+
+func TestWriteJobResults(t *testing.T) {
+	results := []entity.JobResult{{Index: 0, Item: entity.JobItem{Query: "москва"}, Provider: "dadata",
+		Addresses: []*entity.Address{{City: "Москва", Lat: 55.75, Lon: 37.62, HasCoordinates: true, Details: &entity.Data{PostalCode: "101000"}}}}}
+	each := func(fn func(entity.JobResult) error) error {
+		for _, result := range results {
+			if err := fn(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	job := entity.Job{Kind: entity.JobSearch}
+
+	var buf strings.Builder
+	if err := WriteJobResults(&buf, JobFormatNDJSON, job, entity.DetailBasic, each); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "101000") || !strings.Contains(buf.String(), `"geo_lat":"55.75"`) {
+		t.Errorf("expected basic addresses without details, got %s", buf.String())
+	}
+	buf.Reset()
+	WriteJobResults(&buf, JobFormatNDJSON, job, entity.DetailFull, each)
+	if !strings.Contains(buf.String(), "101000") {
+		t.Errorf("expected details with detail=full, got %s", buf.String())
+	}
+	if results[0].Addresses[0].Details == nil {
+		t.Error("expected stored results not to be modified")
+	}
+}
+
+func TestParseJobInput(t *testing.T) {
+	csvInput := "\ufefflat;lng\n55.75;37.62\n;37.62\n91;0\n"
+	items, err := ParseJobInput(strings.NewReader(csvInput), JobFormatCSV, entity.JobGeocode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Lat != 55.75 || items[0].Lng != 37.62 || items[0].Error != "" {
+		t.Fatalf("unexpected items %+v", items)
+	}
+	if items[1].Error == "" || items[2].Error == "" {
+		t.Errorf("expected invalid rows to become item errors, got %+v", items[1:])
+	}
+
+	ndjson := `{"query":"Москва"}` + "\n\n" + `{"lat":55.75,"lon":37.62}` + "\n" + `{"lat":` + "\n"
+	items, err = ParseJobInput(strings.NewReader(ndjson), JobFormatNDJSON, entity.JobSearch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Строка без запроса в задании поиска - ошибка элемента, а не пустой запрос к провайдеру
+	if len(items) != 3 || items[0].Query != "Москва" || items[1].Lng != 37.62 || items[1].Error == "" || items[2].Error == "" {
+		t.Errorf("unexpected items %+v", items)
+	}
+	items, err = ParseJobInput(strings.NewReader("query;city\nМосква;\n ;Москва\n"), JobFormatCSV, entity.JobSearch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Error != "" || items[1].Error == "" {
+		t.Errorf("expected an empty query to become an item error, got %+v", items)
+	}
+
+	// Явный lng: 0 - координата, а не её отсутствие
+	ndjson = `{"lat":51.48,"lng":0,"lon":37.62}` + "\n" + `{"lng":37.62}` + "\n" + `{"lat":91,"lng":0}` + "\n"
+	items, err = ParseJobInput(strings.NewReader(ndjson), JobFormatNDJSON, entity.JobGeocode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Lng != 0 || items[0].Error != "" {
+		t.Fatalf("unexpected items %+v", items)
+	}
+	if items[1].Error == "" || items[2].Error == "" {
+		t.Errorf("expected missing and invalid coordinates to become item errors, got %+v", items[1:])
+	}
+
+	if _, err := ParseJobInput(strings.NewReader("city\nМосква\n"), JobFormatCSV, entity.JobSearch); err == nil {
+		t.Error("expected an error without a query column")
+	}
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+var (
+	streamConcurrencyMu sync.RWMutex
+	streamConcurrency   = 4
+)
+
+// SetStreamConcurrency задаёт, сколько запросов одного потока выполняется одновременно
+func SetStreamConcurrency(n int) {
+	streamConcurrencyMu.Lock()
+	defer streamConcurrencyMu.Unlock()
+	streamConcurrency = n
+}
+
+func currentStreamConcurrency() int {
+	streamConcurrencyMu.RLock()
+	defer streamConcurrencyMu.RUnlock()
+	return max(streamConcurrency, 1)
+}
+
+// StreamRequest - запрос потока. Index - номер запроса, по нему клиент сопоставляет
+// результаты: они приходят в порядке готовности. Err - ошибка разбора, запрос не выполняется.
+type StreamRequest[T any] struct {
+	Index int
+	Req   T
+	Err   error
+}
+
+// StreamResult - результат запроса потока
+type StreamResult struct {
+	Index int
+	Geo   entity.ResponseAddresses
+	Err   error
+}
+
+// HandleGeocodeStream выполняет HandleGeocodeRequest для запросов из in по мере их поступления
+// и отдаёт результаты в out. Закрывает out, когда in закрыт и все результаты отданы.
+func HandleGeocodeStream(ctx context.Context, in <-chan StreamRequest[entity.GeocodeRequest], out chan<- StreamResult, geoService entity.GeoProvider, cache entity.Cache) {
+	runStream(ctx, "geocode", in, out, func(ctx context.Context, req entity.GeocodeRequest) (entity.ResponseAddresses, error) {
+		return HandleGeocodeRequest(ctx, req, geoService, cache)
+	})
+}
+
+// HandleSearchStream - потоковый HandleGeocodeAddressReq
+func HandleSearchStream(ctx context.Context, in <-chan StreamRequest[entity.RequestAddressSearch], out chan<- StreamResult, geoService entity.GeoProvider, cache entity.Cache) {
+	runStream(ctx, "search", in, out, func(ctx context.Context, req entity.RequestAddressSearch) (entity.ResponseAddresses, error) {
+		return HandleGeocodeAddressReq(ctx, req, geoService, cache)
+	})
+}
+
+// runStream читает in в streamConcurrency горутин. Пока out не читают, новые запросы
+// из in не берутся - так медленный читатель результатов придерживает и источник запросов.
+// После отмены ctx запросы больше не читаются, а оставшиеся результаты не отдаются.
+func runStream[T any](ctx context.Context, kind string, in <-chan StreamRequest[T], out chan<- StreamResult, handle func(ctx context.Context, req T) (entity.ResponseAddresses, error)) {
+	adapter.DefaultMetrics.Inc(kind + ".streams")
+	var wg sync.WaitGroup
+	for range currentStreamConcurrency() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var req StreamRequest[T]
+				var ok bool
+				select {
+				case req, ok = <-in:
+					if !ok {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+				result := StreamResult{Index: req.Index, Err: req.Err}
+				if req.Err == nil {
+					adapter.DefaultMetrics.Inc(kind + ".stream_items")
+					result.Geo, result.Err = handle(ctx, req.Req)
+				}
+				select {
+				case out <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(out)
+}
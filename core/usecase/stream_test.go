@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+func TestHandleSearchStream(t *testing.T) {
+	SetStreamConcurrency(2)
+	defer SetStreamConcurrency(4)
+
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	provider := &countingProvider{calls: make(map[string]int)}
+	in := make(chan StreamRequest[entity.RequestAddressSearch])
+	out := make(chan StreamResult)
+	go HandleSearchStream(context.Background(), in, out, provider, adapter.NewMemoryCache(cache))
+
+	queries := []string{"stream-a", "stream-b", "fail", "stream-c", "stream-d"}
+	go func() {
+		defer close(in)
+		for i, q := range queries {
+			in <- StreamRequest[entity.RequestAddressSearch]{Index: i, Req: entity.RequestAddressSearch{Query: q}}
+		}
+		in <- StreamRequest[entity.RequestAddressSearch]{Index: len(queries), Err: &entity.FieldError{Field: "query"}}
+	}()
+
+	// Пока результаты не читают, исполнители не берут новые запросы
+	time.Sleep(50 * time.Millisecond)
+	provider.mu.Lock()
+	started := len(provider.calls)
+	provider.mu.Unlock()
+	if started > 2 {
+		t.Errorf("expected at most 2 requests to start without a reader, got %d", started)
+	}
+
+	seen := make(map[int]StreamResult)
+	for result := range out {
+		seen[result.Index] = result
+	}
+	if len(seen) != len(queries)+1 {
+		t.Fatalf("expected %d results, got %d", len(queries)+1, len(seen))
+	}
+	if seen[0].Err != nil || seen[0].Geo.Addresses[0].City != "stream-a" || seen[2].Err == nil || seen[len(queries)].Err == nil {
+		t.Errorf("unexpected results %+v", seen)
+	}
+	if peak := atomic.LoadInt32(&provider.peak); peak > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", peak)
+	}
+}
+
+func TestHandleSearchStreamCanceled(t *testing.T) {
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan StreamRequest[entity.RequestAddressSearch])
+	out := make(chan StreamResult)
+	go HandleSearchStream(ctx, in, out, slowProvider{}, adapter.NewMemoryCache(cache))
+
+	in <- StreamRequest[entity.RequestAddressSearch]{Req: entity.RequestAddressSearch{Query: "stream-slow"}}
+	cancel()
+	select {
+	case _, ok := <-out:
+		for ok {
+			_, ok = <-out
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the stream to stop after cancel")
+	}
+}
@@ -1,16 +1,39 @@
 package usecase
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
 )
 
-const tokenFilePath = "tokens.json"
+// ErrReservedUsername - имя принадлежит администратору из конфигурации
+var ErrReservedUsername = errors.New("username is reserved")
+
+var (
+	tokenFileMu sync.RWMutex
+	tokenFile   = "tokens.json"
+)
+
+// SetTokenFile задаёт файл, в котором хранятся выданные токены
+func SetTokenFile(path string) {
+	tokenFileMu.Lock()
+	defer tokenFileMu.Unlock()
+	tokenFile = path
+}
+
+func currentTokenFile() string {
+	tokenFileMu.RLock()
+	defer tokenFileMu.RUnlock()
+	return tokenFile
+}
 
 // @Summary Register a new user
 // @Description This endpoint allows you to register a new user with a username and password.
@@ -24,11 +47,19 @@ const tokenFilePath = "tokens.json"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /api/register [post]
 func Register(user *entity.User) error {
+	if _, ok := entity.AdminUsers[user.Username]; ok {
+		return ErrReservedUsername
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
+	id, err := newUserID()
+	if err != nil {
+		return err
+	}
 	entity.Users[user.Username] = entity.User{
+		ID:       id,
 		Username: user.Username,
 		Password: string(hashedPassword),
 	}
@@ -36,6 +67,22 @@ func Register(user *entity.User) error {
 	// Отправляем ответ о успешной регистрации
 }
 
+// AddAdmin заводит администратора с bcrypt-хешем пароля из конфигурации. Пользователи живут
+// только в памяти, поэтому администраторы создаются при каждом запуске, а их имена
+// нельзя занять регистрацией.
+func AddAdmin(username, passwordHash string) error {
+	if _, err := bcrypt.Cost([]byte(passwordHash)); err != nil {
+		return fmt.Errorf("admin %q: invalid password hash: %w", username, err)
+	}
+	id, err := newUserID()
+	if err != nil {
+		return err
+	}
+	entity.Users[username] = entity.User{ID: id, Username: username, Password: passwordHash}
+	entity.AdminUsers[username] = struct{}{}
+	return nil
+}
+
 // @Summary Login a user
 // @Description This endpoint allows a user to log in with their username and password.
 // @Tags users
@@ -56,8 +103,11 @@ func Login(user *entity.User) (string, error) {
 
 	// Если авторизация успешна, создаем токен
 	claims := map[string]interface{}{
-		"user_id": user.Username, // Используем username как user_id
-		"exp":     time.Now().Add(time.Hour * 72).Unix(),
+		entity.UserIDClaim: storedUser.ID,
+		"exp":              time.Now().Add(time.Hour * 72).Unix(),
+	}
+	if _, ok := entity.AdminUsers[user.Username]; ok {
+		claims[entity.RoleClaim] = entity.RoleAdmin
 	}
 	_, tokenString, err := entity.TokenAuth.Encode(claims)
 	if err != nil {
@@ -75,9 +125,17 @@ func Login(user *entity.User) (string, error) {
 	return tokenString, nil // Возвращаем токен
 }
 
+func newUserID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("user id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
 // LoadTokens загружает токены из файла
 func LoadTokens() error {
-	file, err := os.ReadFile(tokenFilePath)
+	file, err := os.ReadFile(currentTokenFile())
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Если файл не существует, просто возвращаем nil
@@ -94,5 +152,5 @@ func SaveTokens() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(tokenFilePath, file, 0644)
+	return os.WriteFile(currentTokenFile(), file, 0644)
 }
@@ -1,39 +1,205 @@
 package usecase
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"sync"
+	"time"
 
 	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
 
 	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
 )
 
-func HandleGeocodeRequest(req entity.GeocodeRequest, geoService entity.GeoProvider, cache *adapter.Cache) (entity.ResponseAddresses, error) {
-	cacheKey := fmt.Sprintf("geocode:%f:%f", req.Lat, req.Lng)
+// geoFlight объединяет одновременные промахи кэша по одному ключу в один запрос к GeoProvider
+var geoFlight = adapter.NewSingleFlight()
 
-	// Проверка кэша
-	if cachedGeo, found := cache.Get(cacheKey); found {
-		return cachedGeo.(entity.ResponseAddresses), nil // Приведение типа
+// CachePolicy задаёт, как use case'ы кэшируют ответы провайдера.
+// Окно отдачи устаревших значений настраивается на самом кэше (adapter.WithStaleGrace).
+type CachePolicy struct {
+	// NegativeTTL - время жизни пустых ответов и ошибок провайдера
+	NegativeTTL time.Duration
+}
+
+var (
+	policyMu    sync.RWMutex
+	cachePolicy = CachePolicy{NegativeTTL: 30 * time.Second}
+)
+
+func SetCachePolicy(p CachePolicy) {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	cachePolicy = p
+}
+
+func currentPolicy() CachePolicy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return cachePolicy
+}
+
+// Timeouts - дедлайны вызовов провайдера по видам запросов, 0 - без собственного дедлайна.
+// Дедлайн отсчитывается от начала вызова провайдера, а не от начала запроса клиента.
+type Timeouts struct {
+	Geocode time.Duration
+	Search  time.Duration
+}
+
+func (t Timeouts) forKind(kind string) time.Duration {
+	switch kind {
+	case "geocode":
+		return t.Geocode
+	case "search":
+		return t.Search
 	}
+	return 0
+}
 
-	// Вызов сервиса
-	geo, err := geoService.GetGeoCoordinatesGeocode(req.Lat, req.Lng)
-	if err != nil {
+var (
+	timeoutsMu sync.RWMutex
+	timeouts   = Timeouts{Geocode: 5 * time.Second, Search: 5 * time.Second}
+)
+
+func SetTimeouts(t Timeouts) {
+	timeoutsMu.Lock()
+	defer timeoutsMu.Unlock()
+	timeouts = t
+}
+
+func currentTimeouts() Timeouts {
+	timeoutsMu.RLock()
+	defer timeoutsMu.RUnlock()
+	return timeouts
+}
+
+// fetchFunc вызывает провайдер. ctx уже ограничен дедлайном из Timeouts.
+type fetchFunc func(ctx context.Context) (entity.ResponseAddresses, error)
+
+// HandleGeocodeRequest не пускает к провайдеру координаты вне допустимых пределов:
+// такой запрос завершается *entity.FieldError
+func HandleGeocodeRequest(ctx context.Context, req entity.GeocodeRequest, geoService entity.GeoProvider, cache entity.Cache) (entity.ResponseAddresses, error) {
+	if err := req.Validate(); err != nil {
 		return entity.ResponseAddresses{}, err
 	}
-	cache.Set(cacheKey, geo)
-	return geo, nil
+	lat, lng := float64(req.Lat), float64(req.Lng)
+	strategy := currentGeoKey()
+	geo, hit, err := cachedFetch(ctx, newGeoCache("geocode", cache), strategy.Key(lat, lng), func(ctx context.Context) (entity.ResponseAddresses, error) {
+		return geoService.GetGeoCoordinatesGeocode(ctx, lat, lng)
+	})
+	if hit {
+		adapter.DefaultMetrics.Inc("geokey." + strategy.Name() + ".hits")
+	} else {
+		adapter.DefaultMetrics.Inc("geokey." + strategy.Name() + ".misses")
+	}
+	return geo, err
+}
+
+func HandleGeocodeAddressReq(ctx context.Context, req entity.RequestAddressSearch, geoService entity.GeoProvider, cache entity.Cache) (entity.ResponseAddresses, error) {
+	geo, _, err := cachedFetch(ctx, newGeoCache("search", cache), req.Query, func(ctx context.Context) (entity.ResponseAddresses, error) {
+		return geoService.GetGeoCoordinatesAddress(ctx, req.Query)
+	})
+	return geo, err
+}
+
+// geoCache - ответы провайдера и его ошибки для одного вида запросов (geocode, search),
+// каждые в своём пространстве ключей
+type geoCache struct {
+	kind    string
+	results *adapter.TypedCache[string, entity.ResponseAddresses]
+	errors  *adapter.TypedCache[string, entity.CachedError]
 }
 
-func HandleGeocodeAddressReq(req entity.RequestAddressSearch, geoService entity.GeoProvider, cache *adapter.Cache) (entity.ResponseAddresses, error) {
-	cacheKey := fmt.Sprintf("search:%s", req.Query)
-	if cachedGeo, found := cache.Get(cacheKey); found {
-		return cachedGeo.(entity.ResponseAddresses), nil
+func newGeoCache(kind string, cache entity.Cache) geoCache {
+	return geoCache{
+		kind:    kind,
+		results: adapter.NewTypedCache[string, entity.ResponseAddresses](cache, kind),
+		errors:  adapter.NewTypedCache[string, entity.CachedError](cache, kind+"-error"),
 	}
-	geo, err := geoService.GetGeoCoordinatesAddress(req.Query)
+}
+
+// cachedFetch отдаёт значение из кэша, а устаревшее - отдаёт и обновляет в фоне.
+// При промахе вызывает fetch один раз на все одновременные запросы с одним ключом.
+// hit == true, если ответ (в том числе ошибка) взят из кэша.
+// Ошибки бэкенда кэша считаются промахом, чтобы его недоступность не ломала запросы.
+// Фоновое обновление не привязано к ctx запроса и ограничено только Timeouts.
+func cachedFetch(ctx context.Context, gc geoCache, key string, fetch fetchFunc) (geo entity.ResponseAddresses, hit bool, err error) {
+	geo, stale, found, err := gc.results.GetStale(ctx, key)
 	if err != nil {
-		return entity.ResponseAddresses{}, err
+		adapter.DefaultMetrics.Inc("cache.errors")
+	}
+	if found {
+		if stale {
+			adapter.DefaultMetrics.Inc(gc.kind + ".stale_served")
+			go fetchShared(context.WithoutCancel(ctx), gc, key, fetch, true)
+		}
+		return geo, true, nil
+	}
+
+	// Устаревшие ошибки не отдаём (Get их пропускает), идём к провайдеру заново
+	cachedErr, found, err := gc.errors.Get(ctx, key)
+	if err != nil {
+		adapter.DefaultMetrics.Inc("cache.errors")
+	}
+	if found {
+		adapter.DefaultMetrics.Inc(gc.kind + ".negative_hits")
+		return entity.ResponseAddresses{}, true, cachedErr
+	}
+
+	geo, err = fetchShared(ctx, gc, key, fetch, false)
+	return geo, false, err
+}
+
+// fetchShared выполняет fetch один раз на все одновременные запросы с одним ключом
+// и кладёт результат в кэш. Счётчики <kind>.upstream_calls и <kind>.deduplicated
+// показывают, сколько вызовов ушло в провайдер и сколько было объединено.
+// При фоновом обновлении (refresh) ошибка не затирает устаревшее значение.
+// Вызов провайдера отменяется, когда его перестают ждать все запросы с этим ключом.
+func fetchShared(ctx context.Context, gc geoCache, key string, fetch fetchFunc, refresh bool) (entity.ResponseAddresses, error) {
+	v, err, shared := geoFlight.DoContext(ctx, gc.results.Key(key), func(ctx context.Context) (interface{}, error) {
+		adapter.DefaultMetrics.Inc(gc.kind + ".upstream_calls")
+		callCtx := ctx
+		if timeout := currentTimeouts().forKind(gc.kind); timeout > 0 {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		geo, err := fetch(callCtx)
+		if errors.Is(err, context.DeadlineExceeded) {
+			adapter.DefaultMetrics.Inc(gc.kind + ".timeouts")
+		}
+
+		// Запись в кэш не должна срываться из-за истёкшего дедлайна провайдера
+		ctx = context.WithoutCancel(ctx)
+		negativeTTL := currentPolicy().NegativeTTL
+		var cacheErr error
+		switch {
+		case err != nil:
+			// Отменённые вызовы ничего не говорят о запросе, их не кэшируем
+			// как и отказы автомата защиты и лимитов - это состояние провайдера, а не ответ на запрос
+			transient := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) ||
+				errors.Is(err, entity.ErrCircuitOpen) || errors.Is(err, entity.ErrRateLimited) ||
+				errors.Is(err, entity.ErrQuotaExhausted)
+			if !refresh && !transient && negativeTTL > 0 {
+				cacheErr = gc.errors.Set(ctx, key, entity.NewCachedError(err), negativeTTL)
+			}
+		case len(geo.Addresses) == 0 && negativeTTL > 0:
+			cacheErr = gc.results.Set(ctx, key, geo, negativeTTL)
+		default:
+			cacheErr = gc.results.Set(ctx, key, geo, 0)
+		}
+		if cacheErr != nil {
+			adapter.DefaultMetrics.Inc("cache.errors")
+		}
+
+		if err != nil {
+			return entity.ResponseAddresses{}, err
+		}
+		return geo, nil
+	})
+	if shared {
+		adapter.DefaultMetrics.Inc(gc.kind + ".deduplicated")
 	}
-	cache.Set(cacheKey, geo)
-	return geo, nil
+	// При отмене ctx результата нет
+	geo, _ := v.(entity.ResponseAddresses)
+	return geo, err
 }
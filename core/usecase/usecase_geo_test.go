@@ -0,0 +1,197 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// slowProvider отвечает только после отмены ctx
+type slowProvider struct {
+	entity.GeoProvider
+}
+
+func (slowProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	<-ctx.Done()
+	return entity.ResponseAddresses{}, ctx.Err()
+}
+
+func TestHandleGeocodeAddressReqTimeout(t *testing.T) {
+	SetTimeouts(Timeouts{Search: 20 * time.Millisecond})
+	defer SetTimeouts(Timeouts{Geocode: 5 * time.Second, Search: 5 * time.Second})
+
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	_, err := HandleGeocodeAddressReq(context.Background(), entity.RequestAddressSearch{Query: "slow"}, slowProvider{}, adapter.NewMemoryCache(cache))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if cache.Len() != 0 {
+		t.Error("expected timeout not to be cached")
+	}
+}
+
+func TestHandleGeocodeAddressReqCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	_, err := HandleGeocodeAddressReq(ctx, entity.RequestAddressSearch{Query: "canceled"}, slowProvider{}, adapter.NewMemoryCache(cache))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+}
+
+// gateProvider отвечает, получив значение из gate (или когда он закрыт), и нумерует ответы: City - номер вызова
+type gateProvider struct {
+	entity.GeoProvider
+	gate  chan struct{}
+	calls int32
+}
+
+func (p *gateProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	n := atomic.AddInt32(&p.calls, 1)
+	select {
+	case <-p.gate:
+	case <-ctx.Done():
+		return entity.ResponseAddresses{}, ctx.Err()
+	}
+	return entity.ResponseAddresses{Addresses: []*entity.Address{{City: strconv.Itoa(int(n))}}}, nil
+}
+
+func TestHandleGeocodeAddressReqDedup(t *testing.T) {
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	provider := &gateProvider{gate: make(chan struct{})}
+	memCache := adapter.NewMemoryCache(cache)
+
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			geo, err := HandleGeocodeAddressReq(context.Background(), entity.RequestAddressSearch{Query: "dedup"}, provider, memCache)
+			if err == nil && geo.Addresses[0].City != "1" {
+				err = errors.New("unexpected city " + geo.Addresses[0].City)
+			}
+			errs <- err
+		}()
+	}
+	// Даём всем запросам дойти до ожидания общего вызова
+	time.Sleep(50 * time.Millisecond)
+	close(provider.gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if calls := atomic.LoadInt32(&provider.calls); calls != 1 {
+		t.Errorf("expected %d concurrent misses to make one provider call, got %d", n, calls)
+	}
+}
+
+func TestHandleGeocodeAddressReqStaleWhileRevalidate(t *testing.T) {
+	cache := adapter.NewCache(20*time.Millisecond, adapter.WithStaleGrace(time.Minute))
+	defer cache.Close()
+	// Каждое значение в gate пропускает один вызов провайдера
+	provider := &gateProvider{gate: make(chan struct{}, 1)}
+	// Отпускаем обновления, начатые после проверок
+	defer close(provider.gate)
+	memCache := adapter.NewMemoryCache(cache)
+	// geoFlight общий для всех тестов, поэтому ключ у каждого запуска свой
+	query := fmt.Sprintf("stale-%p", provider)
+	search := func() string {
+		t.Helper()
+		geo, err := HandleGeocodeAddressReq(context.Background(), entity.RequestAddressSearch{Query: query}, provider, memCache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return geo.Addresses[0].City
+	}
+
+	provider.gate <- struct{}{}
+	if city := search(); city != "1" {
+		t.Fatalf("expected the first answer, got %s", city)
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	// Устаревшее значение отдаётся сразу, а обновление уходит в фон один раз на все запросы
+	for range 5 {
+		if city := search(); city != "1" {
+			t.Fatalf("expected the stale answer, got %s", city)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if calls := atomic.LoadInt32(&provider.calls); calls != 2 {
+		t.Fatalf("expected one background refresh, got %d provider calls", calls)
+	}
+
+	provider.gate <- struct{}{}
+	deadline := time.Now().Add(time.Second)
+	for search() != "2" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the entry to be refreshed in the background")
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+// failingProvider отвечает ошибкой err и считает вызовы
+type failingProvider struct {
+	entity.GeoProvider
+	err   error
+	calls int32
+}
+
+func (p *failingProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	atomic.AddInt32(&p.calls, 1)
+	return entity.ResponseAddresses{}, p.err
+}
+
+func TestHandleGeocodeAddressReqNegativeCache(t *testing.T) {
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	memCache := adapter.NewMemoryCache(cache)
+
+	for _, tc := range []struct {
+		query  string
+		err    error
+		status int
+	}{
+		{"negative-429", fmt.Errorf("all geo providers failed: %w", entity.NewUpstreamError("dadata", 429, []byte("slow down"))), 429},
+		{"negative-503", entity.NewUpstreamError("dadata", 503, nil), 503},
+		{"negative-plain", errors.New("malformed response"), 0},
+	} {
+		provider := &failingProvider{err: tc.err}
+		for attempt := range 2 {
+			_, err := HandleGeocodeAddressReq(context.Background(), entity.RequestAddressSearch{Query: tc.query}, provider, memCache)
+			if err == nil || err.Error() != tc.err.Error() {
+				t.Fatalf("%s attempt %d: expected %q, got %v", tc.query, attempt, tc.err, err)
+			}
+			// Ошибка из кэша сохраняет статус провайдера
+			var upstream *entity.UpstreamError
+			if got := errors.As(err, &upstream); got != (tc.status != 0) || got && upstream.StatusCode != tc.status {
+				t.Errorf("%s attempt %d: expected upstream status %d, got %+v", tc.query, attempt, tc.status, upstream)
+			}
+		}
+		if calls := atomic.LoadInt32(&provider.calls); calls != 1 {
+			t.Errorf("%s: expected the second call to be served from the negative cache, got %d calls", tc.query, calls)
+		}
+	}
+}
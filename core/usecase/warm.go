@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+// WarmItem - один запрос из списка прогрева: поисковая строка или пара координат
+type WarmItem struct {
+	Query    string
+	Lat, Lng float64
+	IsCoords bool
+}
+
+func (i WarmItem) String() string {
+	if i.IsCoords {
+		return fmt.Sprintf("%f,%f", i.Lat, i.Lng)
+	}
+	return i.Query
+}
+
+// ParseWarmList читает список прогрева: по запросу на строку, строки вида "lat,lng"
+// считаются координатами, пустые строки и строки с # пропускаются
+func ParseWarmList(r io.Reader) ([]WarmItem, error) {
+	var items []WarmItem
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if lat, lng, ok := parseLatLng(line); ok {
+			items = append(items, WarmItem{Lat: lat, Lng: lng, IsCoords: true})
+			continue
+		}
+		items = append(items, WarmItem{Query: line})
+	}
+	return items, scanner.Err()
+}
+
+func parseLatLng(line string) (float64, float64, bool) {
+	latStr, lngStr, ok := strings.Cut(line, ",")
+	if !ok {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	lng, err := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return lat, lng, true
+}
+
+type WarmOptions struct {
+	// Concurrency - сколько запросов выполняется одновременно, не больше MaxWarmConcurrency
+	Concurrency int
+	// Rate - не больше Rate вызовов провайдера в секунду, 0 - без ограничения
+	Rate float64
+	// Progress вызывается после каждого обработанного запроса, вызовы не пересекаются
+	Progress func(WarmReport)
+}
+
+type WarmError struct {
+	Item  string `json:"item"`
+	Error string `json:"error"`
+}
+
+type WarmReport struct {
+	Total         int         `json:"total"`
+	Done          int         `json:"done"`
+	Failed        int         `json:"failed"`
+	UpstreamCalls int64       `json:"upstream_calls"`
+	Errors        []WarmError `json:"errors,omitempty"`
+	Running       bool        `json:"running"`
+}
+
+const (
+	// maxWarmErrors ограничивает количество ошибок в отчёте
+	maxWarmErrors = 100
+	// MaxWarmConcurrency - предел WarmOptions.Concurrency, чтобы прогрев не занял весь провайдер
+	MaxWarmConcurrency = 32
+)
+
+// Warm прогоняет список через HandleGeocodeRequest/HandleGeocodeAddressReq, заполняя кэш.
+// Уже закэшированные запросы провайдер не тратят.
+func Warm(ctx context.Context, items []WarmItem, geoService entity.GeoProvider, cache entity.Cache, opts WarmOptions) WarmReport {
+	opts.Concurrency = min(max(opts.Concurrency, 1), MaxWarmConcurrency)
+	provider := &warmProvider{GeoProvider: geoService}
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		provider.tick = ticker.C
+	}
+
+	var mu sync.Mutex
+	report := WarmReport{Total: len(items), Running: true}
+	jobs := make(chan WarmItem)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				var err error
+				if item.IsCoords {
+					_, err = HandleGeocodeRequest(ctx, entity.GeocodeRequest{Lat: entity.Coordinate(item.Lat), Lng: entity.Coordinate(item.Lng)}, provider, cache)
+				} else {
+					_, err = HandleGeocodeAddressReq(ctx, entity.RequestAddressSearch{Query: item.Query}, provider, cache)
+				}
+
+				mu.Lock()
+				report.Done++
+				if err != nil {
+					report.Failed++
+					if len(report.Errors) < maxWarmErrors {
+						report.Errors = append(report.Errors, WarmError{Item: item.String(), Error: err.Error()})
+					}
+				}
+				report.UpstreamCalls = atomic.LoadInt64(&provider.calls)
+				if opts.Progress != nil {
+					opts.Progress(report)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, item := range items {
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	report.UpstreamCalls = atomic.LoadInt64(&provider.calls)
+	report.Running = false
+	return report
+}
+
+// warmProvider считает вызовы провайдера и ограничивает их частоту при прогреве
+type warmProvider struct {
+	entity.GeoProvider
+	tick  <-chan time.Time
+	calls int64
+}
+
+func (p *warmProvider) wait(ctx context.Context) error {
+	if p.tick == nil {
+		return nil
+	}
+	select {
+	case <-p.tick:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *warmProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	if err := p.wait(ctx); err != nil {
+		return entity.ResponseAddresses{}, err
+	}
+	atomic.AddInt64(&p.calls, 1)
+	return p.GeoProvider.GetGeoCoordinatesAddress(ctx, query)
+}
+
+func (p *warmProvider) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	if err := p.wait(ctx); err != nil {
+		return entity.ResponseAddresses{}, err
+	}
+	atomic.AddInt64(&p.calls, 1)
+	return p.GeoProvider.GetGeoCoordinatesGeocode(ctx, lat, lng)
+}
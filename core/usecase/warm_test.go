@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"studentgit.kata.academy/Zhodaran/go-kata/adapters/adapter"
+	"studentgit.kata.academy/Zhodaran/go-kata/core/entity"
+)
+
+type stubProvider struct {
+	entity.GeoProvider
+}
+
+func (stubProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	if query == "bad" {
+		return entity.ResponseAddresses{}, errors.New("upstream failure")
+	}
+	return entity.ResponseAddresses{Addresses: []*entity.Address{{City: query}}}, nil
+}
+
+func (stubProvider) GetGeoCoordinatesGeocode(ctx context.Context, lat float64, lng float64) (entity.ResponseAddresses, error) {
+	return entity.ResponseAddresses{Addresses: []*entity.Address{{City: "Москва"}}}, nil
+}
+
+func TestWarm(t *testing.T) {
+	list := "# утренний список\nМосква Тверская 1\n55.7539, 37.6208\nbad\n\nМосква Тверская 1\n"
+	items, err := ParseWarmList(strings.NewReader(list))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 || !items[1].IsCoords {
+		t.Fatalf("unexpected parsed items %+v", items)
+	}
+
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	report := Warm(context.Background(), items, stubProvider{}, adapter.NewMemoryCache(cache), WarmOptions{Concurrency: 1})
+
+	if report.Done != 4 || report.Failed != 1 || len(report.Errors) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	// Повторный запрос уже лежит в кэше и провайдер не тратит
+	if report.UpstreamCalls != 3 {
+		t.Errorf("expected 3 upstream calls, got %d", report.UpstreamCalls)
+	}
+	if _, ok := cache.Get("search:Москва Тверская 1"); !ok {
+		t.Error("expected search result to be cached")
+	}
+}
+
+// concurrencyProvider запоминает наибольшее число одновременных вызовов
+type concurrencyProvider struct {
+	entity.GeoProvider
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (p *concurrencyProvider) GetGeoCoordinatesAddress(ctx context.Context, query string) (entity.ResponseAddresses, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.peak = max(p.peak, p.inFlight)
+	p.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return entity.ResponseAddresses{Addresses: []*entity.Address{{City: query}}}, nil
+}
+
+func TestWarmClampsConcurrency(t *testing.T) {
+	items := make([]WarmItem, 200)
+	for i := range items {
+		items[i] = WarmItem{Query: fmt.Sprintf("warm-clamp-%d", i)}
+	}
+	cache := adapter.NewCache(time.Minute)
+	defer cache.Close()
+	provider := &concurrencyProvider{}
+
+	report := Warm(context.Background(), items, provider, adapter.NewMemoryCache(cache), WarmOptions{Concurrency: 10000})
+	if report.Done != len(items) {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if provider.peak > MaxWarmConcurrency {
+		t.Errorf("expected at most %d concurrent calls, got %d", MaxWarmConcurrency, provider.peak)
+	}
+}